@@ -17,10 +17,33 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/distribution/reference"
+	jsonpatch "github.com/evanphx/json-patch"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"time"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/yaml"
 )
 
 // ApplicationSnapshotSpec defines the desired state of ApplicationSnapshot
@@ -38,7 +61,8 @@ type ApplicationSnapshotSpec struct {
 	// DisplayDescription is a user-visible, user definable description for the resource (and is not used for any functional behaviour)
 	DisplayDescription string `json:"displayDescription,omitempty"`
 
-	// Type is an optional definiton of how the ApplicationSnapshot was constructed
+	// Type is an optional definiton of how the ApplicationSnapshot was constructed.
+	// Supported values are 'component' and 'composite'.
 	Type string `json:"type,omitempty"`
 
 	// Components field contains the sets of components to deploy as part of this snapshot.
@@ -47,14 +71,76 @@ type ApplicationSnapshotSpec struct {
 	// Artifacts is a placeholder section for 'artifact links' we want to maintain to other AppStudio resources.
 	// See Environment API doc for details.
 	Artifacts SnapshotArtifacts `json:"artifacts,omitempty"`
+
+	// TTLSecondsAfterCompletion is the number of seconds after its completion the ApplicationSnapshot may be deleted
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	TTLSecondsAfterCompletion *int32 `json:"ttlSecondsAfterCompletion,omitempty"`
+}
+
+const (
+	// ApplicationSnapshotTypeComponent is the type of an ApplicationSnapshot constructed from the build of a single component
+	ApplicationSnapshotTypeComponent string = "component"
+
+	// ApplicationSnapshotTypeComposite is the type of an ApplicationSnapshot constructed from all the components of an Application
+	ApplicationSnapshotTypeComposite string = "composite"
+)
+
+// legacyApplicationSnapshotTypes maps the legacy (lowercased) types of ApplicationSnapshots to the supported types
+var legacyApplicationSnapshotTypes = map[string]string{
+	"single":           ApplicationSnapshotTypeComponent,
+	"single-component": ApplicationSnapshotTypeComponent,
+	"application":      ApplicationSnapshotTypeComposite,
+	"multi-component":  ApplicationSnapshotTypeComposite,
 }
 
+// DefaultApplicationSnapshotTypeLabelValue is the label value used for an ApplicationSnapshot without a type
+const DefaultApplicationSnapshotTypeLabelValue = "unspecified"
+
+const (
+	// MaxDisplayNameLength is the maximum length of the DisplayName of an ApplicationSnapshot
+	MaxDisplayNameLength = 256
+
+	// MaxDisplayDescriptionLength is the maximum length of the DisplayDescription of an ApplicationSnapshot
+	MaxDisplayDescriptionLength = 2048
+
+	// MaxTTLSecondsAfterCompletion is the maximum TTLSecondsAfterCompletion of an ApplicationSnapshot (one year)
+	MaxTTLSecondsAfterCompletion = 365 * 24 * 60 * 60
+
+	// MaxComponentWeight is the maximum Weight of a component, and the total the weights are normalized to
+	MaxComponentWeight = 100
+
+	// MaxApplicationSnapshotConditions is the maximum number of conditions kept in the status of an ApplicationSnapshot
+	// when it is sanitized
+	MaxApplicationSnapshotConditions = 16
+)
+
 // ApplicationSnapshotReason represents a reason for the release "Succeeded" condition
 type ApplicationSnapshotReason string
 
+const (
+	// ApplicationSnapshotConditionTypeSucceeded is the condition type reporting whether the ApplicationSnapshot succeeded
+	ApplicationSnapshotConditionTypeSucceeded string = "Succeeded"
+
+	// ApplicationSnapshotConditionTypeValidated is the condition type reporting whether the ApplicationSnapshot was validated
+	ApplicationSnapshotConditionTypeValidated string = "Validated"
+
+	// ApplicationSnapshotConditionTypeReleased is the condition type reporting whether the ApplicationSnapshot was released
+	ApplicationSnapshotConditionTypeReleased string = "Released"
+)
+
+// AllConditionTypes returns the list of all the condition types which can be set on an ApplicationSnapshot.
+func AllConditionTypes() []string {
+	return []string{
+		ApplicationSnapshotConditionTypeSucceeded,
+		ApplicationSnapshotConditionTypeValidated,
+		ApplicationSnapshotConditionTypeReleased,
+	}
+}
+
 const (
 	// applicationSnapshotConditionType is the type used when setting a release status condition
-	applicationSnapshotConditionType string = "Succeeded"
+	applicationSnapshotConditionType string = ApplicationSnapshotConditionTypeSucceeded
 
 	// ApplicationSnapshotReasonInitialized is the reason set when ApplicationSnapshot is initialized
 	ApplicationSnapshotReasonInitialized ApplicationSnapshotReason = "Initialized"
@@ -70,12 +156,74 @@ const (
 
 	// ApplicationSnapshotReasonSucceeded is the reason set when the integration test PipelineRun has succeeded
 	ApplicationSnapshotReasonSucceeded ApplicationSnapshotReason = "Succeeded"
+
+	// ApplicationSnapshotReasonSkipped is the reason set when ApplicationSnapshot integration tests were skipped by policy
+	ApplicationSnapshotReasonSkipped ApplicationSnapshotReason = "Skipped"
+
+	// ApplicationSnapshotReasonCancelled is the reason set when ApplicationSnapshot integration tests were cancelled
+	ApplicationSnapshotReasonCancelled ApplicationSnapshotReason = "Cancelled"
+
+	// ApplicationSnapshotReasonTimedOut is the reason set when ApplicationSnapshot integration tests timed out
+	ApplicationSnapshotReasonTimedOut ApplicationSnapshotReason = "TimedOut"
+)
+
+const (
+	// ApplicationSnapshotPhasePending is the phase of an ApplicationSnapshot whose processing hasn't started yet
+	ApplicationSnapshotPhasePending string = "Pending"
+
+	// ApplicationSnapshotPhaseRunning is the phase of an ApplicationSnapshot whose integration tests are running
+	ApplicationSnapshotPhaseRunning string = "Running"
+
+	// ApplicationSnapshotPhaseSucceeded is the phase of an ApplicationSnapshot whose integration tests succeeded
+	ApplicationSnapshotPhaseSucceeded string = "Succeeded"
+
+	// ApplicationSnapshotPhaseSkipped is the phase of an ApplicationSnapshot whose integration tests were skipped
+	ApplicationSnapshotPhaseSkipped string = "Skipped"
+
+	// ApplicationSnapshotPhaseFailed is the phase of an ApplicationSnapshot whose integration tests failed
+	ApplicationSnapshotPhaseFailed string = "Failed"
+
+	// ApplicationSnapshotPhaseInvalid is the phase of an ApplicationSnapshot which failed validation
+	ApplicationSnapshotPhaseInvalid string = "Invalid"
+)
+
+const (
+	// ApplicationSnapshotStatusOwnerAnnotation is the annotation used to record the identity of the controller which
+	// currently owns (is allowed to write) the status conditions of an ApplicationSnapshot
+	ApplicationSnapshotStatusOwnerAnnotation string = "appstudio.redhat.com/status-owner"
+
+	// ApplicationSnapshotApprovedAnnotation is the annotation used to record that an ApplicationSnapshot was approved for release,
+	// when its value is "true"
+	ApplicationSnapshotApprovedAnnotation string = "appstudio.redhat.com/approved"
+
+	// ApplicationSnapshotRollbackTargetAnnotation is the annotation used to record the namespaced name of the
+	// ApplicationSnapshot to roll back to when the release of an ApplicationSnapshot fails
+	ApplicationSnapshotRollbackTargetAnnotation string = "appstudio.redhat.com/rollback-target"
+
+	// ApplicationSnapshotStatusOverriddenAnnotation is the annotation used to record that the outcome of an
+	// ApplicationSnapshot was forcibly overridden, when its value is "true"
+	ApplicationSnapshotStatusOverriddenAnnotation string = "appstudio.redhat.com/status-overridden"
+
+	// ApplicationSnapshotSupersededByAnnotation is the annotation used to record the namespaced name of the
+	// ApplicationSnapshot which superseded an ApplicationSnapshot, when it was cancelled because of it
+	ApplicationSnapshotSupersededByAnnotation string = "appstudio.redhat.com/superseded-by"
 )
 
 func (asr ApplicationSnapshotReason) String() string {
 	return string(asr)
 }
 
+// IsTerminal checks whether the ApplicationSnapshot can't progress any further once its Succeeded condition has this reason
+func (asr ApplicationSnapshotReason) IsTerminal() bool {
+	switch asr {
+	case ApplicationSnapshotReasonSucceeded, ApplicationSnapshotReasonTestsFailed, ApplicationSnapshotReasonValidationError,
+		ApplicationSnapshotReasonCancelled, ApplicationSnapshotReasonSkipped, ApplicationSnapshotReasonTimedOut:
+		return true
+	default:
+		return false
+	}
+}
+
 // ApplicationSnapshotComponent
 type ApplicationSnapshotComponent struct {
 
@@ -84,6 +232,16 @@ type ApplicationSnapshotComponent struct {
 
 	// ContainerImage is the container image to use when deploying the component, as part of a Snapshot
 	ContainerImage string `json:"containerImage"`
+
+	// Labels are optional labels attached to the component, for example to record the team owning it
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Weight is the optional percentage of the traffic routed to the component, used by canary tooling
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	Weight *int32 `json:"weight,omitempty"`
 }
 
 // SnapshotArtifacts is a placeholder section for 'artifact links' we want to maintain to other AppStudio resources.
@@ -96,8 +254,34 @@ type SnapshotArtifacts struct {
 	// - Until this API is stabilized, consumers of the API may store any unstructured JSON/YAML data here,
 	//   but no backwards compatibility will be preserved.
 	UnstableFields *apiextensionsv1.JSON `json:"unstableFields,omitempty"`
+
+	// Images links the container images of the components to the source code they were built from
+	// +optional
+	Images []ImageSource `json:"images,omitempty"`
+}
+
+// ImageSource links the container image of a component to the source code it was built from
+type ImageSource struct {
+
+	// Component is the name of the component whose container image was built from this source
+	Component string `json:"component"`
+
+	// URL is the URL of the source code repository the container image was built from
+	// +optional
+	URL string `json:"url,omitempty"`
+
+	// Revision is the revision (for example, the commit SHA) of the source code the container image was built from
+	// +optional
+	Revision string `json:"revision,omitempty"`
 }
 
+// MaxArtifactsUnstableFieldsSize is the maximum size, in bytes, of the raw JSON stored in the Artifacts UnstableFields
+const MaxArtifactsUnstableFieldsSize = 64 * 1024
+
+// MaxApplicationSnapshotSize is the maximum size, in bytes, of an ApplicationSnapshot, within the limit of the size
+// of the objects stored by the API server
+const MaxApplicationSnapshotSize = 1536 * 1024
+
 // ApplicationSnapshotStatus defines the observed state of ApplicationSnapshot
 type ApplicationSnapshotStatus struct {
 	// StartTime is the time when the Release PipelineRun was created and set to run
@@ -116,6 +300,85 @@ type ApplicationSnapshotStatus struct {
 	// +kubebuilder:validation:Pattern=^[a-z0-9]([-a-z0-9]*[a-z0-9])?\/[a-z0-9]([-a-z0-9]*[a-z0-9])?$
 	// +optional
 	ReleasePipelineRun string `json:"releasePipelineRun,omitempty"`
+
+	// IntegrationTestPipelineRun contains the namespaced name of the integration test PipelineRun executed for this snapshot
+	// +kubebuilder:validation:Pattern=^[a-z0-9]([-a-z0-9]*[a-z0-9])?\/[a-z0-9]([-a-z0-9]*[a-z0-9])?$
+	// +optional
+	IntegrationTestPipelineRun string `json:"integrationTestPipelineRun,omitempty"`
+
+	// BuildPipelineRuns contains, for each component name, the namespaced name of the build PipelineRun which produced the component container image
+	// +optional
+	BuildPipelineRuns map[string]string `json:"buildPipelineRuns,omitempty"`
+
+	// RetryCount is the number of times the processing of the ApplicationSnapshot was retried
+	// +optional
+	RetryCount int32 `json:"retryCount,omitempty"`
+
+	// LastRetryTime is the time the processing of the ApplicationSnapshot was last retried
+	// +optional
+	LastRetryTime *metav1.Time `json:"lastRetryTime,omitempty"`
+
+	// ObservedApplicationGeneration is the generation of the Application the ApplicationSnapshot was last evaluated against
+	// +optional
+	ObservedApplicationGeneration int64 `json:"observedApplicationGeneration,omitempty"`
+
+	// Phase is a single phase summarizing the status of the ApplicationSnapshot, computed from its Succeeded condition
+	// +optional
+	Phase string `json:"phase,omitempty"`
+
+	// Bindings contains the ApplicationSnapshotEnvironmentBindings which bind the ApplicationSnapshot to an environment
+	// +optional
+	Bindings []BindingRef `json:"bindings,omitempty"`
+
+	// ReleasedEnvironments contains the names of the environments the ApplicationSnapshot was released to
+	// +optional
+	ReleasedEnvironments []string `json:"releasedEnvironments,omitempty"`
+
+	// Components contains the integration test results of the individual components of the ApplicationSnapshot
+	// +optional
+	Components []ApplicationSnapshotComponentStatus `json:"components,omitempty"`
+}
+
+// ApplicationSnapshotComponentStatus contains the integration test results of a component of an ApplicationSnapshot
+type ApplicationSnapshotComponentStatus struct {
+
+	// Name is the name of the component
+	Name string `json:"name"`
+
+	// Failed is true when the integration tests of the component failed
+	// +optional
+	Failed bool `json:"failed,omitempty"`
+
+	// Message is a human readable message describing the integration test results of the component
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// Primary designates the component as the primary failure of the ApplicationSnapshot, when several components failed
+	// +optional
+	Primary bool `json:"primary,omitempty"`
+}
+
+// BindingRef references an ApplicationSnapshotEnvironmentBinding which binds an ApplicationSnapshot to an environment
+type BindingRef struct {
+
+	// Environment is the name of the environment the ApplicationSnapshot is bound to
+	Environment string `json:"environment"`
+
+	// Binding is the namespaced name of the ApplicationSnapshotEnvironmentBinding
+	// +kubebuilder:validation:Pattern=^[a-z0-9]([-a-z0-9]*[a-z0-9])?\/[a-z0-9]([-a-z0-9]*[a-z0-9])?$
+	Binding string `json:"binding"`
+}
+
+// namespacedNameRegex matches a namespaced name, in the '<namespace>/<name>' format
+var namespacedNameRegex = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?\/[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// validateNamespacedName checks that the given string is a namespaced name, in the '<namespace>/<name>' format.
+func validateNamespacedName(nn string) error {
+	if !namespacedNameRegex.MatchString(nn) {
+		return fmt.Errorf("%q is not a valid namespaced name, it must match the '<namespace>/<name>' format", nn)
+	}
+
+	return nil
 }
 
 //+kubebuilder:object:root=true
@@ -132,14 +395,112 @@ type ApplicationSnapshot struct {
 	Status ApplicationSnapshotStatus `json:"status,omitempty"`
 }
 
+// NewApplicationSnapshotFromImages returns a new ApplicationSnapshot with a component for each of the given images, named
+// after the prefix and the ComponentFingerprint of its components. nil is returned if any of its fields is invalid.
+func NewApplicationSnapshotFromImages(namePrefix, application string, images map[string]string) *ApplicationSnapshot {
+	if len(images) == 0 || len(validation.IsDNS1123Subdomain(application)) > 0 {
+		return nil
+	}
+
+	snapshot := &ApplicationSnapshot{
+		Spec: ApplicationSnapshotSpec{
+			Application: application,
+			Components:  make([]ApplicationSnapshotComponent, 0, len(images)),
+		},
+	}
+	for name, image := range images {
+		snapshot.Spec.Components = append(snapshot.Spec.Components, ApplicationSnapshotComponent{Name: name, ContainerImage: image})
+	}
+	sort.Slice(snapshot.Spec.Components, func(i, j int) bool {
+		return snapshot.Spec.Components[i].Name < snapshot.Spec.Components[j].Name
+	})
+
+	snapshot.Name = namePrefix + "-" + snapshot.ComponentFingerprint()
+	if len(validation.IsDNS1123Subdomain(snapshot.Name)) > 0 {
+		return nil
+	}
+	if len(validateComponents(snapshot.Spec.Components, field.NewPath("spec").Child("components"))) > 0 {
+		return nil
+	}
+
+	return snapshot
+}
+
+// WithUpdatedComponentImage returns an unnamed copy of the ApplicationSnapshot spec, labels and annotations, with the
+// container image of the given component replaced by the given image. The annotations recording the approval, status
+// or rollback target of the ApplicationSnapshot aren't copied.
+func (a *ApplicationSnapshot) WithUpdatedComponentImage(component, image string) (*ApplicationSnapshot, error) {
+	if a.Spec.Application == "" {
+		return nil, fmt.Errorf("ApplicationSnapshot %s doesn't reference an application", a.Name)
+	}
+	if _, err := reference.ParseNormalizedNamed(image); err != nil {
+		return nil, fmt.Errorf("invalid image %q: %w", image, err)
+	}
+
+	copied := a.DeepCopy()
+	updated := &ApplicationSnapshot{
+		TypeMeta: copied.TypeMeta,
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:    copied.Namespace,
+			GenerateName: copied.Spec.Application + "-",
+			Labels:       copied.Labels,
+			Annotations:  copied.Annotations,
+		},
+		Spec: copied.Spec,
+	}
+	for _, annotation := range []string{
+		ApplicationSnapshotApprovedAnnotation,
+		ApplicationSnapshotStatusOwnerAnnotation,
+		ApplicationSnapshotStatusOverriddenAnnotation,
+		ApplicationSnapshotSupersededByAnnotation,
+		ApplicationSnapshotRollbackTargetAnnotation,
+	} {
+		delete(updated.Annotations, annotation)
+	}
+
+	for i := range updated.Spec.Components {
+		if updated.Spec.Components[i].Name == component {
+			updated.Spec.Components[i].ContainerImage = image
+			return updated, nil
+		}
+	}
+
+	return nil, fmt.Errorf("component %s not found in ApplicationSnapshot %s", component, a.Name)
+}
+
+// ReconcileRequest returns a reconcile request for the ApplicationSnapshot, identified by its namespaced name.
+func (a *ApplicationSnapshot) ReconcileRequest() reconcile.Request {
+	return reconcile.Request{NamespacedName: types.NamespacedName{Namespace: a.Namespace, Name: a.Name}}
+}
+
+// PageKey returns a '<creationTimestamp>|<namespace>|<name>' key which sorts the ApplicationSnapshots by creation time
+func (a *ApplicationSnapshot) PageKey() string {
+	return fmt.Sprintf("%s|%s|%s", a.CreationTimestamp.UTC().Format(time.RFC3339), a.Namespace, a.Name)
+}
+
 // HasStarted checks whether the ApplicationSnapshot has a valid start time set in its status.
 func (a *ApplicationSnapshot) HasStarted() bool {
 	return a.Status.StartTime != nil && !a.Status.StartTime.IsZero()
 }
 
-// HasSucceeded checks whether the ApplicationSnapshot has succeeded or not.
+// HasSucceeded checks whether the ApplicationSnapshot has succeeded or not, that is whether its Succeeded condition is
+// True. A skipped ApplicationSnapshot is considered to have succeeded, so that it remains eligible for promotion.
 func (a *ApplicationSnapshot) HasSucceeded() bool {
-	return !meta.IsStatusConditionTrue(a.Status.Conditions, applicationSnapshotConditionType)
+	return meta.IsStatusConditionTrue(a.Status.Conditions, applicationSnapshotConditionType)
+}
+
+// IsOrphaned checks whether the ApplicationSnapshot does not reference an Application, in which case it can never be bound.
+func (a *ApplicationSnapshot) IsOrphaned() bool {
+	return a.Spec.Application == ""
+}
+
+// healthCheckExpression is a CEL expression which evaluates to true when an ApplicationSnapshot has succeeded
+const healthCheckExpression = "status.conditions.exists(c, c.type == '" + applicationSnapshotConditionType + "' && c.status == 'True')"
+
+// HealthCheckExpression returns a CEL expression which evaluates to true when the ApplicationSnapshot has succeeded,
+// for use as the 'current' expression of a Flux Kustomization 'healthCheckExprs' entry.
+func (a *ApplicationSnapshot) HealthCheckExpression() string {
+	return healthCheckExpression
 }
 
 // IsDone returns a boolean indicating whether the ApplicationSnapshot's status indicates that it is done or not.
@@ -152,10 +513,129 @@ func (a *ApplicationSnapshot) IsDone() bool {
 	return false
 }
 
+// CompletionTimeSentinel is the far-future time returned by EffectiveCompletionTime for an ApplicationSnapshot which
+// hasn't completed yet, so that running ApplicationSnapshots sort after the completed ones.
+var CompletionTimeSentinel = metav1.NewTime(time.Date(9999, time.December, 31, 23, 59, 59, 0, time.UTC))
+
+// EffectiveCompletionTime returns the completion time of the ApplicationSnapshot, or CompletionTimeSentinel when it
+// hasn't completed yet. It is meant to be used when sorting ApplicationSnapshots by completion time.
+func (a *ApplicationSnapshot) EffectiveCompletionTime() metav1.Time {
+	if a.Status.CompletionTime == nil {
+		return CompletionTimeSentinel
+	}
+
+	return *a.Status.CompletionTime
+}
+
+// IsExpired checks whether the ApplicationSnapshot is done and its TTLSecondsAfterCompletion has elapsed since its
+// completion time. An ApplicationSnapshot without a TTL or a completion time never expires.
+func (a *ApplicationSnapshot) IsExpired(now time.Time) bool {
+	if !a.IsDone() || a.Status.CompletionTime == nil || a.Spec.TTLSecondsAfterCompletion == nil {
+		return false
+	}
+
+	ttl := time.Duration(*a.Spec.TTLSecondsAfterCompletion) * time.Second
+	return !now.Before(a.Status.CompletionTime.Add(ttl))
+}
+
+// Duration returns the time elapsed between the start and the completion of the ApplicationSnapshot, or zero if it
+// hasn't both started and completed.
+func (a *ApplicationSnapshot) Duration() time.Duration {
+	if !a.HasStarted() || a.Status.CompletionTime == nil {
+		return 0
+	}
+
+	return a.Status.CompletionTime.Sub(a.Status.StartTime.Time)
+}
+
+// ElapsedSinceStart returns the time elapsed since the ApplicationSnapshot started, truncated to the second, if it did
+func (a *ApplicationSnapshot) ElapsedSinceStart(now time.Time) (elapsed string, ok bool) {
+	if !a.HasStarted() {
+		return "", false
+	}
+
+	duration := now.Sub(a.Status.StartTime.Time)
+	if duration < 0 {
+		duration = 0
+	}
+
+	return duration.Truncate(time.Second).String(), true
+}
+
+// MetSLO checks whether the ApplicationSnapshot completed within the given SLO. measurable is false when it isn't done
+// or never started.
+func (a *ApplicationSnapshot) MetSLO(slo time.Duration) (met bool, measurable bool) {
+	if !a.IsDone() || !a.HasStarted() || a.Status.CompletionTime == nil {
+		return false, false
+	}
+
+	return a.Duration() <= slo, true
+}
+
+// Phase returns a single phase summarizing the status of the ApplicationSnapshot, computed from the status and reason
+// of the condition returned by PhaseCondition. An ApplicationSnapshot without such a condition is Pending.
+func (a *ApplicationSnapshot) Phase() string {
+	condition := a.PhaseCondition()
+	if condition == nil {
+		return ApplicationSnapshotPhasePending
+	}
+
+	switch condition.Status {
+	case metav1.ConditionTrue:
+		if condition.Reason == ApplicationSnapshotReasonSkipped.String() {
+			return ApplicationSnapshotPhaseSkipped
+		}
+		return ApplicationSnapshotPhaseSucceeded
+	case metav1.ConditionFalse:
+		if condition.Reason == ApplicationSnapshotReasonValidationError.String() {
+			return ApplicationSnapshotPhaseInvalid
+		}
+		return ApplicationSnapshotPhaseFailed
+	default:
+		return ApplicationSnapshotPhaseRunning
+	}
+}
+
+// SyncPhaseToStatus writes the current Phase of the ApplicationSnapshot into its status. It is called whenever the
+// Succeeded condition is set by one of the Mark functions.
+func (a *ApplicationSnapshot) SyncPhaseToStatus() {
+	a.Status.Phase = a.Phase()
+}
+
+// PhaseCondition returns the condition whose status and reason drive the Phase of the ApplicationSnapshot, or nil
+// if it isn't set. The phase is currently always computed from the Succeeded condition.
+func (a *ApplicationSnapshot) PhaseCondition() *metav1.Condition {
+	return meta.FindStatusCondition(a.Status.Conditions, applicationSnapshotConditionType)
+}
+
+// TestOutcome returns whether the integration tests of the ApplicationSnapshot passed, along with the reason of the
+// Succeeded condition. ok is false when the tests are still running or haven't started.
+func (a *ApplicationSnapshot) TestOutcome() (passed bool, reason ApplicationSnapshotReason, ok bool) {
+	condition := a.PhaseCondition()
+	if condition == nil || condition.Status == metav1.ConditionUnknown {
+		return false, "", false
+	}
+
+	return condition.Status == metav1.ConditionTrue, ApplicationSnapshotReason(condition.Reason), true
+}
+
+// hasTerminalCondition checks whether the Succeeded condition of the ApplicationSnapshot has a terminal reason
+func (a *ApplicationSnapshot) hasTerminalCondition() bool {
+	condition := meta.FindStatusCondition(a.Status.Conditions, applicationSnapshotConditionType)
+
+	return condition != nil && ApplicationSnapshotReason(condition.Reason).IsTerminal()
+}
+
+// hasFinalCondition checks whether the Succeeded condition of the ApplicationSnapshot has a terminal reason, or is done
+// with a registered completion time, in which case the Mark functions leave it unchanged.
+func (a *ApplicationSnapshot) hasFinalCondition() bool {
+	return a.hasTerminalCondition() || (a.IsDone() && a.Status.CompletionTime != nil)
+}
+
 // MarkFailed registers the completion time and changes the Succeeded condition to False with
 // the provided reason and message.
 func (a *ApplicationSnapshot) MarkFailed(reason ApplicationSnapshotReason, message string) {
-	if a.IsDone() && a.Status.CompletionTime != nil {
+	if a.hasFinalCondition() {
 		return
 	}
 
@@ -164,18 +644,35 @@ func (a *ApplicationSnapshot) MarkFailed(reason ApplicationSnapshotReason, messa
 
 }
 
-// MarkInvalid changes the Succeeded condition to False with the provided reason and message.
+// ForceMarkFailed registers a new completion time and changes the Succeeded condition to False, even when the
+// ApplicationSnapshot is already done, and records the ApplicationSnapshotStatusOverriddenAnnotation.
+func (a *ApplicationSnapshot) ForceMarkFailed(reason ApplicationSnapshotReason, message string) {
+	a.Status.CompletionTime = &metav1.Time{Time: time.Now()}
+	a.setStatusConditionWithMessage(metav1.ConditionFalse, reason, message)
+
+	if a.Annotations == nil {
+		a.Annotations = map[string]string{}
+	}
+	a.Annotations[ApplicationSnapshotStatusOverriddenAnnotation] = "true"
+}
+
+// MarkInvalid registers the completion time and changes the Succeeded condition to False with the provided reason
+// and message.
 func (a *ApplicationSnapshot) MarkInvalid(reason ApplicationSnapshotReason, message string) {
-	if a.IsDone() {
+	if a.hasFinalCondition() {
 		return
 	}
+	if reason == "" {
+		reason = ApplicationSnapshotReasonValidationError
+	}
 
+	a.Status.CompletionTime = &metav1.Time{Time: time.Now()}
 	a.setStatusConditionWithMessage(metav1.ConditionFalse, reason, message)
 }
 
 // MarkRunning registers the start time and changes the Succeeded condition to Unknown.
 func (a *ApplicationSnapshot) MarkRunning() {
-	if a.HasStarted() && a.Status.StartTime != nil {
+	if a.hasFinalCondition() || (a.HasStarted() && a.Status.StartTime != nil) {
 		return
 	}
 
@@ -183,9 +680,30 @@ func (a *ApplicationSnapshot) MarkRunning() {
 	a.setStatusCondition(metav1.ConditionUnknown, ApplicationSnapshotReasonTestsRunning)
 }
 
+// IsSkipped checks whether the ApplicationSnapshot integration tests were skipped.
+func (a *ApplicationSnapshot) IsSkipped() bool {
+	condition := meta.FindStatusCondition(a.Status.Conditions, applicationSnapshotConditionType)
+	if condition != nil {
+		return condition.Status == metav1.ConditionTrue && condition.Reason == ApplicationSnapshotReasonSkipped.String()
+	}
+
+	return false
+}
+
+// MarkSkipped registers the completion time and changes the Succeeded condition to True with the Skipped reason
+// and the provided message.
+func (a *ApplicationSnapshot) MarkSkipped(message string) {
+	if a.hasFinalCondition() {
+		return
+	}
+
+	a.Status.CompletionTime = &metav1.Time{Time: time.Now()}
+	a.setStatusConditionWithMessage(metav1.ConditionTrue, ApplicationSnapshotReasonSkipped, message)
+}
+
 // MarkSucceeded registers the completion time and changes the Succeeded condition to True.
 func (a *ApplicationSnapshot) MarkSucceeded() {
-	if a.IsDone() && a.Status.CompletionTime != nil {
+	if a.hasFinalCondition() {
 		return
 	}
 
@@ -193,30 +711,1648 @@ func (a *ApplicationSnapshot) MarkSucceeded() {
 	a.setStatusCondition(metav1.ConditionTrue, ApplicationSnapshotReasonSucceeded)
 }
 
-// SetCondition creates a new condition with the given status and reason. Then, it sets this new condition,
-// unsetting previous conditions with the same type as necessary.
-func (a *ApplicationSnapshot) setStatusCondition(status metav1.ConditionStatus, reason ApplicationSnapshotReason) {
-	a.setStatusConditionWithMessage(status, reason, "")
+// AutoReleasePolicy defines the requirements an ApplicationSnapshot must meet to be automatically released.
+// +kubebuilder:object:generate=false
+type AutoReleasePolicy struct {
+	// RequireDigests requires the container image of every component to be pinned by digest
+	RequireDigests bool
+
+	// RequireApproval requires the ApplicationSnapshot to be approved, via the ApplicationSnapshotApprovedAnnotation
+	RequireApproval bool
+
+	// MaxAge is the maximum age of the ApplicationSnapshot, since its creation. Zero means there is no maximum age.
+	MaxAge time.Duration
+
+	// FreezeWindows are the change freeze windows during which no ApplicationSnapshot can be automatically released
+	FreezeWindows []FreezeWindow
 }
 
-// SetCondition creates a new condition with the given status, reason and message. Then, it sets this new condition,
-// unsetting previous conditions with the same type as necessary.
-func (a *ApplicationSnapshot) setStatusConditionWithMessage(status metav1.ConditionStatus, reason ApplicationSnapshotReason, message string) {
-	meta.SetStatusCondition(&a.Status.Conditions, metav1.Condition{
-		Type:    applicationSnapshotConditionType,
-		Status:  status,
-		Reason:  reason.String(),
-		Message: message,
-	})
+// FreezeWindow is a change freeze window, during which ApplicationSnapshots must not be automatically released.
+// +kubebuilder:object:generate=false
+type FreezeWindow struct {
+	// Start is the time the freeze window starts at, inclusive
+	Start time.Time
+
+	// End is the time the freeze window ends at, exclusive
+	End time.Time
 }
 
-//+kubebuilder:object:root=true
+// AutoReleaseEligible checks whether the ApplicationSnapshot can be automatically released according to the given policy,
+// along with the reason when it can't.
+func (a *ApplicationSnapshot) AutoReleaseEligible(policy AutoReleasePolicy) (bool, string) {
+	if !a.HasSucceeded() {
+		return false, "the snapshot has not succeeded"
+	}
 
-// ApplicationSnapshotList contains a list of ApplicationSnapshot
-type ApplicationSnapshotList struct {
-	metav1.TypeMeta `json:",inline"`
-	metav1.ListMeta `json:"metadata,omitempty"`
-	Items           []ApplicationSnapshot `json:"items"`
+	if policy.RequireDigests {
+		for _, component := range a.Spec.Components {
+			named, err := reference.ParseNormalizedNamed(component.ContainerImage)
+			if err != nil {
+				return false, fmt.Sprintf("the container image of component %q can't be parsed: %v", component.Name, err)
+			}
+			if _, isDigested := named.(reference.Digested); !isDigested {
+				return false, fmt.Sprintf("the container image of component %q is not pinned by digest", component.Name)
+			}
+		}
+	}
+
+	if policy.RequireApproval && a.Annotations[ApplicationSnapshotApprovedAnnotation] != "true" {
+		return false, "the snapshot has not been approved"
+	}
+
+	if policy.MaxAge > 0 && time.Since(a.CreationTimestamp.Time) > policy.MaxAge {
+		return false, fmt.Sprintf("the snapshot is older than the maximum age of %s", policy.MaxAge)
+	}
+
+	if a.InFreezeWindow(policy.FreezeWindows, time.Now()) {
+		return false, "a change freeze window is in effect"
+	}
+
+	return true, ""
+}
+
+// InFreezeWindow checks whether the given time is within any of the given freeze windows, start included and end excluded
+func (a *ApplicationSnapshot) InFreezeWindow(windows []FreezeWindow, now time.Time) bool {
+	for _, window := range windows {
+		if !now.Before(window.Start) && now.Before(window.End) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RecordRetry increments the retry count of the ApplicationSnapshot, and registers the current time as its last retry time.
+func (a *ApplicationSnapshot) RecordRetry() {
+	a.Status.RetryCount++
+	a.Status.LastRetryTime = &metav1.Time{Time: time.Now()}
+}
+
+// NextRetryTime returns the time at which the processing of the ApplicationSnapshot should next be retried, using an
+// exponential backoff. The zero time is returned if it was never retried, and the last retry time if base or max
+// isn't positive.
+func (a *ApplicationSnapshot) NextRetryTime(base time.Duration, max time.Duration) time.Time {
+	if a.Status.LastRetryTime == nil {
+		return time.Time{}
+	}
+	if base <= 0 || max <= 0 {
+		return a.Status.LastRetryTime.Time
+	}
+
+	backoff := base
+	for i := int32(0); i < a.Status.RetryCount && backoff < max; i++ {
+		backoff *= 2
+	}
+	if backoff > max {
+		backoff = max
+	}
+
+	return a.Status.LastRetryTime.Add(backoff)
+}
+
+// TrySetStatusOwner records the given owner as the single writer of the status conditions, unless they are held by a
+// different owner, and returns whether the given owner holds them.
+func (a *ApplicationSnapshot) TrySetStatusOwner(owner string) bool {
+	currentOwner, exists := a.Annotations[ApplicationSnapshotStatusOwnerAnnotation]
+	if exists && currentOwner != "" && currentOwner != owner {
+		return false
+	}
+
+	if a.Annotations == nil {
+		a.Annotations = map[string]string{}
+	}
+	a.Annotations[ApplicationSnapshotStatusOwnerAnnotation] = owner
+
+	return true
+}
+
+// ReleaseStatusOwner removes the status owner annotation, if it is currently held by the given owner.
+func (a *ApplicationSnapshot) ReleaseStatusOwner(owner string) {
+	if a.Annotations[ApplicationSnapshotStatusOwnerAnnotation] == owner {
+		delete(a.Annotations, ApplicationSnapshotStatusOwnerAnnotation)
+	}
+}
+
+// SetRollbackTarget records the namespaced name of the ApplicationSnapshot to roll back to if the release of this
+// ApplicationSnapshot fails. An error is returned if the namespaced name is not in the '<namespace>/<name>' format.
+func (a *ApplicationSnapshot) SetRollbackTarget(nn string) error {
+	if err := validateNamespacedName(nn); err != nil {
+		return err
+	}
+
+	if a.Annotations == nil {
+		a.Annotations = map[string]string{}
+	}
+	a.Annotations[ApplicationSnapshotRollbackTargetAnnotation] = nn
+
+	return nil
+}
+
+// GetRollbackTarget returns the namespaced name of the ApplicationSnapshot to roll back to, and whether a valid one
+// was recorded. A rollback target which is not in the '<namespace>/<name>' format is ignored.
+func (a *ApplicationSnapshot) GetRollbackTarget() (string, bool) {
+	nn, exists := a.Annotations[ApplicationSnapshotRollbackTargetAnnotation]
+	if !exists || validateNamespacedName(nn) != nil {
+		return "", false
+	}
+
+	return nn, true
+}
+
+// SetSupersededBy records the namespaced name of the ApplicationSnapshot which superseded this ApplicationSnapshot.
+// An error is returned if the namespaced name is not in the '<namespace>/<name>' format.
+func (a *ApplicationSnapshot) SetSupersededBy(nn string) error {
+	if err := validateNamespacedName(nn); err != nil {
+		return err
+	}
+
+	if a.Annotations == nil {
+		a.Annotations = map[string]string{}
+	}
+	a.Annotations[ApplicationSnapshotSupersededByAnnotation] = nn
+
+	return nil
+}
+
+// GetSupersededBy returns the namespaced name of the ApplicationSnapshot which superseded this ApplicationSnapshot,
+// and whether a valid one was recorded. A value which is not in the '<namespace>/<name>' format is ignored.
+func (a *ApplicationSnapshot) GetSupersededBy() (string, bool) {
+	nn, exists := a.Annotations[ApplicationSnapshotSupersededByAnnotation]
+	if !exists || validateNamespacedName(nn) != nil {
+		return "", false
+	}
+
+	return nn, true
+}
+
+// AddBinding records once that the ApplicationSnapshot is bound to the given environment by the
+// ApplicationSnapshotEnvironmentBinding with the given '<namespace>/<name>' namespaced name.
+func (a *ApplicationSnapshot) AddBinding(env, nn string) error {
+	if err := validateNamespacedName(nn); err != nil {
+		return err
+	}
+
+	for _, binding := range a.Status.Bindings {
+		if binding.Environment == env && binding.Binding == nn {
+			return nil
+		}
+	}
+	a.Status.Bindings = append(a.Status.Bindings, BindingRef{Environment: env, Binding: nn})
+
+	return nil
+}
+
+// BindingsForEnvironment returns the namespaced names of the ApplicationSnapshotEnvironmentBindings which bind the
+// ApplicationSnapshot to the given environment.
+func (a *ApplicationSnapshot) BindingsForEnvironment(env string) []string {
+	bindings := []string{}
+	for _, binding := range a.Status.Bindings {
+		if binding.Environment == env {
+			bindings = append(bindings, binding.Binding)
+		}
+	}
+
+	return bindings
+}
+
+// MarkReleasedToEnvironment records that the ApplicationSnapshot was released to the given environment, for staged
+// rollouts where the release completes for some environments only. An environment is only recorded once.
+func (a *ApplicationSnapshot) MarkReleasedToEnvironment(env string) {
+	if a.IsReleasedTo(env) {
+		return
+	}
+
+	a.Status.ReleasedEnvironments = append(a.Status.ReleasedEnvironments, env)
+}
+
+// IsReleasedTo checks whether the ApplicationSnapshot was released to the given environment.
+func (a *ApplicationSnapshot) IsReleasedTo(env string) bool {
+	for _, releasedEnv := range a.Status.ReleasedEnvironments {
+		if releasedEnv == env {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RecordApplicationGeneration records the generation of the Application the ApplicationSnapshot was evaluated against.
+func (a *ApplicationSnapshot) RecordApplicationGeneration(gen int64) {
+	a.Status.ObservedApplicationGeneration = gen
+}
+
+// ApplicationDrifted checks whether the given generation of the Application differs from the one the ApplicationSnapshot
+// was last evaluated against.
+func (a *ApplicationSnapshot) ApplicationDrifted(currentGen int64) bool {
+	return a.Status.ObservedApplicationGeneration == 0 || a.Status.ObservedApplicationGeneration != currentGen
+}
+
+// SetBuildPipelineRun records the namespaced name of the build PipelineRun which produced the container image of the
+// given component. An error is returned if the namespaced name is not in the '<namespace>/<name>' format.
+func (a *ApplicationSnapshot) SetBuildPipelineRun(component, nn string) error {
+	if err := validateNamespacedName(nn); err != nil {
+		return err
+	}
+
+	if a.Status.BuildPipelineRuns == nil {
+		a.Status.BuildPipelineRuns = map[string]string{}
+	}
+	a.Status.BuildPipelineRuns[component] = nn
+
+	return nil
+}
+
+// GetBuildPipelineRun returns the namespaced name of the build PipelineRun which produced the container image of the
+// given component, and whether it was recorded.
+func (a *ApplicationSnapshot) GetBuildPipelineRun(component string) (string, bool) {
+	nn, exists := a.Status.BuildPipelineRuns[component]
+	return nn, exists
+}
+
+// ParsedComponentImages returns the parsed container image of each component of the ApplicationSnapshot, by component name
+func (a *ApplicationSnapshot) ParsedComponentImages() (map[string]reference.Named, error) {
+	parsedImages := map[string]reference.Named{}
+	errs := []error{}
+
+	for _, component := range a.Spec.Components {
+		named, err := reference.ParseNormalizedNamed(component.ContainerImage)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("unable to parse container image %q of component %q: %v", component.ContainerImage, component.Name, err))
+			continue
+		}
+		parsedImages[component.Name] = named
+	}
+
+	if len(errs) > 0 {
+		return nil, utilerrors.NewAggregate(errs)
+	}
+
+	return parsedImages, nil
+}
+
+// ValidateArtifacts checks that the raw JSON of the Artifacts UnstableFields, if present, is no larger than
+// MaxArtifactsUnstableFieldsSize and is valid JSON.
+func (a *ApplicationSnapshot) ValidateArtifacts() error {
+	return validateArtifacts(a.Spec.Artifacts, field.NewPath("spec").Child("artifacts")).ToAggregate()
+}
+
+// EstimatedSize returns the size, in bytes, of the JSON representation of the ApplicationSnapshot, which approximates
+// the size stored by the API server. 0 is returned if the ApplicationSnapshot can't be marshaled.
+func (a *ApplicationSnapshot) EstimatedSize() int {
+	size, err := a.marshaledSize()
+	if err != nil {
+		return 0
+	}
+
+	return size
+}
+
+// ValidateSizeBudget checks that the EstimatedSize of the ApplicationSnapshot is no larger than the given budget, in
+// bytes, such as MaxApplicationSnapshotSize. An error is also returned if the ApplicationSnapshot can't be marshaled.
+func (a *ApplicationSnapshot) ValidateSizeBudget(max int) error {
+	return validateSizeBudget(a, max, field.NewPath("spec")).ToAggregate()
+}
+
+// marshaledSize returns the size, in bytes, of the JSON representation of the ApplicationSnapshot.
+func (a *ApplicationSnapshot) marshaledSize() (int, error) {
+	raw, err := json.Marshal(a)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(raw), nil
+}
+
+// ValidateStatusConsistency checks that the times, conditions and integration test PipelineRun in the status of the
+// ApplicationSnapshot are consistent with each other.
+func (a *ApplicationSnapshot) ValidateStatusConsistency() error {
+	return validateStatusConsistency(a, field.NewPath("status")).ToAggregate()
+}
+
+// ValidateSingleRegistry checks that the container images of all the components of the ApplicationSnapshot come from
+// the same registry.
+func (a *ApplicationSnapshot) ValidateSingleRegistry() error {
+	return validateSingleRegistry(a.Spec.Components, field.NewPath("spec").Child("components")).ToAggregate()
+}
+
+// ValidateImagesAllowed checks that the container image of each component matches at least one of the given allow
+// patterns, if any.
+func (a *ApplicationSnapshot) ValidateImagesAllowed(patterns []*regexp.Regexp) error {
+	if len(patterns) == 0 {
+		return nil
+	}
+
+	allErrs := field.ErrorList{}
+	fldPath := field.NewPath("spec").Child("components")
+	for i, component := range a.Spec.Components {
+		allowed := false
+		for _, pattern := range patterns {
+			if pattern.MatchString(component.ContainerImage) {
+				allowed = true
+				break
+			}
+		}
+
+		if !allowed {
+			allErrs = append(allErrs, field.Invalid(fldPath.Index(i).Child("containerImage"), component.ContainerImage,
+				fmt.Sprintf("image of component %s does not match any of the allowed image patterns", component.Name)))
+		}
+	}
+
+	return allErrs.ToAggregate()
+}
+
+// ValidateSecureRegistries checks that no component image is pulled over plain HTTP, that is with an explicit 'http://'
+// prefix or from localhost or a loopback address.
+func (a *ApplicationSnapshot) ValidateSecureRegistries() error {
+	allErrs := field.ErrorList{}
+	fldPath := field.NewPath("spec").Child("components")
+	for i, component := range a.Spec.Components {
+		if !isInsecureImage(component.ContainerImage) {
+			continue
+		}
+
+		allErrs = append(allErrs, field.Invalid(fldPath.Index(i).Child("containerImage"), component.ContainerImage,
+			fmt.Sprintf("image of component %s would be pulled from its registry over plain HTTP", component.Name)))
+	}
+
+	return allErrs.ToAggregate()
+}
+
+// isInsecureImage checks whether the image has an explicit 'http://' prefix, or comes from localhost or a loopback
+// address.
+func isInsecureImage(image string) bool {
+	if strings.HasPrefix(strings.ToLower(image), "http://") {
+		return true
+	}
+
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return false
+	}
+
+	host := reference.Domain(named)
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.Trim(host, "[]")
+
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// ValidateArtifactImages checks that every ImageSource of the Artifacts references a component of the ApplicationSnapshot,
+// returning an error listing the ImageSources which reference unknown components.
+func (a *ApplicationSnapshot) ValidateArtifactImages() error {
+	return validateArtifactImages(a.Spec, field.NewPath("spec").Child("artifacts").Child("images")).ToAggregate()
+}
+
+// ArtifactKeysDiff describes the keys added, removed and changed between two versions of the Artifacts
+// +kubebuilder:object:generate=false
+type ArtifactKeysDiff struct {
+	// Added maps the added keys to their current value
+	Added map[string]interface{} `json:"added,omitempty"`
+
+	// Removed maps the removed keys to their previous value
+	Removed map[string]interface{} `json:"removed,omitempty"`
+
+	// Changed maps the changed keys to their previous and current values
+	Changed map[string]ArtifactValueChange `json:"changed,omitempty"`
+}
+
+// ArtifactValueChange describes the previous and current values of a changed Artifacts key.
+// +kubebuilder:object:generate=false
+type ArtifactValueChange struct {
+	Previous interface{} `json:"previous"`
+	Current  interface{} `json:"current"`
+}
+
+// ArtifactsDiff returns a JSON document describing the changes of the UnstableFields keys and ImageSources of the
+// Artifacts between the previous ApplicationSnapshot and this one.
+func (a *ApplicationSnapshot) ArtifactsDiff(previous *ApplicationSnapshot) ([]byte, error) {
+	previousArtifacts := SnapshotArtifacts{}
+	if previous != nil {
+		previousArtifacts = previous.Spec.Artifacts
+	}
+
+	previousFields, err := unstableFieldsAsMap(previousArtifacts.UnstableFields)
+	if err != nil {
+		return nil, fmt.Errorf("invalid previous unstableFields: %w", err)
+	}
+	currentFields, err := unstableFieldsAsMap(a.Spec.Artifacts.UnstableFields)
+	if err != nil {
+		return nil, fmt.Errorf("invalid unstableFields: %w", err)
+	}
+
+	return json.Marshal(struct {
+		UnstableFields ArtifactKeysDiff `json:"unstableFields"`
+		Images         ArtifactKeysDiff `json:"images"`
+	}{
+		UnstableFields: diffArtifactKeys(previousFields, currentFields),
+		Images:         diffArtifactKeys(imageSourcesAsMap(previousArtifacts.Images), imageSourcesAsMap(a.Spec.Artifacts.Images)),
+	})
+}
+
+// unstableFieldsAsMap returns the top-level keys of the UnstableFields, which must be a JSON object if present.
+func unstableFieldsAsMap(unstableFields *apiextensionsv1.JSON) (map[string]interface{}, error) {
+	fields := map[string]interface{}{}
+	if unstableFields == nil || len(unstableFields.Raw) == 0 {
+		return fields, nil
+	}
+
+	if err := json.Unmarshal(unstableFields.Raw, &fields); err != nil {
+		return nil, err
+	}
+
+	return fields, nil
+}
+
+// imageSourcesAsMap returns the ImageSources keyed by component.
+func imageSourcesAsMap(images []ImageSource) map[string]interface{} {
+	sources := map[string]interface{}{}
+	for _, image := range images {
+		sources[image.Component] = image
+	}
+
+	return sources
+}
+
+// diffArtifactKeys compares the previous and current values of every key. Values are compared by their JSON
+// representation, so that decoded and typed values can be mixed.
+func diffArtifactKeys(previous, current map[string]interface{}) ArtifactKeysDiff {
+	diff := ArtifactKeysDiff{}
+
+	for key, value := range current {
+		previousValue, exists := previous[key]
+		if !exists {
+			if diff.Added == nil {
+				diff.Added = map[string]interface{}{}
+			}
+			diff.Added[key] = value
+			continue
+		}
+
+		previousJSON, _ := json.Marshal(previousValue)
+		currentJSON, _ := json.Marshal(value)
+		if !bytes.Equal(previousJSON, currentJSON) {
+			if diff.Changed == nil {
+				diff.Changed = map[string]ArtifactValueChange{}
+			}
+			diff.Changed[key] = ArtifactValueChange{Previous: previousValue, Current: value}
+		}
+	}
+
+	for key, value := range previous {
+		if _, exists := current[key]; !exists {
+			if diff.Removed == nil {
+				diff.Removed = map[string]interface{}{}
+			}
+			diff.Removed[key] = value
+		}
+	}
+
+	return diff
+}
+
+// PrimaryFailure returns the status of the failed component designated as Primary, or else of the first failed component
+func (a *ApplicationSnapshot) PrimaryFailure() (status *ApplicationSnapshotComponentStatus, ok bool) {
+	for i := range a.Status.Components {
+		if a.Status.Components[i].Failed && a.Status.Components[i].Primary {
+			return &a.Status.Components[i], true
+		}
+	}
+
+	for i := range a.Status.Components {
+		if a.Status.Components[i].Failed {
+			return &a.Status.Components[i], true
+		}
+	}
+
+	return nil, false
+}
+
+// ComponentLabel returns the value of the given label of the given component, and whether the component exists and
+// has the label.
+func (a *ApplicationSnapshot) ComponentLabel(component, key string) (string, bool) {
+	for _, c := range a.Spec.Components {
+		if c.Name == component {
+			value, exists := c.Labels[key]
+			return value, exists
+		}
+	}
+
+	return "", false
+}
+
+// TotalWeight returns the sum of the weights of the components of the ApplicationSnapshot. Components without a
+// weight are ignored.
+func (a *ApplicationSnapshot) TotalWeight() int32 {
+	total := int32(0)
+	for _, component := range a.Spec.Components {
+		if component.Weight != nil {
+			total += *component.Weight
+		}
+	}
+
+	return total
+}
+
+// Deduplicate removes the components with the same name and container image as a previous component, and returns the
+// number of components removed.
+func (a *ApplicationSnapshot) Deduplicate() int {
+	type nameAndImage struct {
+		name  string
+		image string
+	}
+
+	seen := map[nameAndImage]bool{}
+	components := a.Spec.Components[:0]
+	for _, component := range a.Spec.Components {
+		key := nameAndImage{name: component.Name, image: component.ContainerImage}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		components = append(components, component)
+	}
+
+	removed := len(a.Spec.Components) - len(components)
+	a.Spec.Components = components
+
+	return removed
+}
+
+// NormalizeWeights evenly distributes the weight left by the components which have one between the other components,
+// so that the weights sum to MaxComponentWeight.
+func (a *ApplicationSnapshot) NormalizeWeights() error {
+	remaining := int32(MaxComponentWeight) - a.TotalWeight()
+	if remaining < 0 {
+		return fmt.Errorf("the total weight of the components %d exceeds %d", a.TotalWeight(), MaxComponentWeight)
+	}
+
+	unweighted := []int{}
+	for i, component := range a.Spec.Components {
+		if component.Weight == nil {
+			unweighted = append(unweighted, i)
+		}
+	}
+	if len(unweighted) == 0 {
+		return nil
+	}
+
+	share, rest := remaining/int32(len(unweighted)), remaining%int32(len(unweighted))
+	for n, i := range unweighted {
+		weight := share
+		if int32(n) < rest {
+			weight++
+		}
+		a.Spec.Components[i].Weight = &weight
+	}
+
+	return nil
+}
+
+// ComponentTag returns the tag of the container image of the given component, if the component exists and its image
+// has a tag.
+func (a *ApplicationSnapshot) ComponentTag(name string) (string, bool) {
+	for _, component := range a.Spec.Components {
+		if component.Name != name {
+			continue
+		}
+
+		named, err := reference.ParseNormalizedNamed(component.ContainerImage)
+		if err != nil {
+			return "", false
+		}
+		if tagged, isTagged := named.(reference.Tagged); isTagged {
+			return tagged.Tag(), true
+		}
+
+		return "", false
+	}
+
+	return "", false
+}
+
+// ForEachComponent calls the given function with the index and a pointer to each component, until it returns an error
+func (a *ApplicationSnapshot) ForEachComponent(fn func(i int, c *ApplicationSnapshotComponent) error) error {
+	for i := range a.Spec.Components {
+		if err := fn(i, &a.Spec.Components[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// StaleComponents returns the names of the components whose container image differs from the given image of the
+// Application component.
+func (a *ApplicationSnapshot) StaleComponents(current map[string]string) []string {
+	staleComponents := []string{}
+
+	for _, component := range a.Spec.Components {
+		currentImage, exists := current[component.Name]
+		if exists && currentImage != component.ContainerImage {
+			staleComponents = append(staleComponents, component.Name)
+		}
+	}
+
+	return staleComponents
+}
+
+// MissingApplicationComponents returns the names of the given Application components which are not present in the
+// ApplicationSnapshot.
+func (a *ApplicationSnapshot) MissingApplicationComponents(appComponents []string) []string {
+	missing := []string{}
+
+	componentNames := map[string]bool{}
+	for _, component := range a.Spec.Components {
+		componentNames[component.Name] = true
+	}
+
+	for _, appComponent := range appComponents {
+		if !componentNames[appComponent] {
+			missing = append(missing, appComponent)
+		}
+	}
+
+	return missing
+}
+
+// ChurnScore returns the number of components which were added, removed or updated relative to the given baseline
+func (a *ApplicationSnapshot) ChurnScore(baseline *ApplicationSnapshot) int {
+	if baseline == nil {
+		return len(a.Spec.Components)
+	}
+
+	baselineImages := map[string]string{}
+	for _, component := range baseline.Spec.Components {
+		baselineImages[component.Name] = component.ContainerImage
+	}
+
+	score := 0
+	seen := map[string]bool{}
+	for _, component := range a.Spec.Components {
+		seen[component.Name] = true
+
+		baselineImage, exists := baselineImages[component.Name]
+		if !exists || baselineImage != component.ContainerImage {
+			// The component was either added or updated
+			score++
+		}
+	}
+
+	for name := range baselineImages {
+		if !seen[name] {
+			// The component was removed
+			score++
+		}
+	}
+
+	return score
+}
+
+// componentFingerprintLength is the number of hexadecimal characters kept in the component fingerprint
+const componentFingerprintLength = 16
+
+// ComponentFingerprint returns a short hexadecimal fingerprint of the sorted name and image pairs of the components,
+// which is a valid label value.
+func (a *ApplicationSnapshot) ComponentFingerprint() string {
+	pairs := make([]string, 0, len(a.Spec.Components))
+	for _, component := range a.Spec.Components {
+		pairs = append(pairs, component.Name+"="+component.ContainerImage)
+	}
+	sort.Strings(pairs)
+
+	sum := sha256.Sum256([]byte(strings.Join(pairs, "\n")))
+	return hex.EncodeToString(sum[:])[:componentFingerprintLength]
+}
+
+// NormalizeType lowercases the type of the ApplicationSnapshot, and maps the known legacy types to the supported ones
+func (a *ApplicationSnapshot) NormalizeType() {
+	snapshotType := strings.ToLower(strings.TrimSpace(a.Spec.Type))
+
+	if snapshotType == ApplicationSnapshotTypeComponent || snapshotType == ApplicationSnapshotTypeComposite {
+		a.Spec.Type = snapshotType
+	} else if supportedType, isLegacy := legacyApplicationSnapshotTypes[snapshotType]; isLegacy {
+		a.Spec.Type = supportedType
+	}
+}
+
+// TypeLabelValue returns the type of the ApplicationSnapshot sanitized into a valid label value, or
+// DefaultApplicationSnapshotTypeLabelValue when it has none.
+func (a *ApplicationSnapshot) TypeLabelValue() string {
+	labelValue := strings.Map(func(r rune) rune {
+		if isAlphanumeric(r) || r == '-' || r == '_' || r == '.' {
+			return r
+		}
+		return '-'
+	}, a.Spec.Type)
+
+	labelValue = strings.TrimFunc(labelValue, func(r rune) bool { return !isAlphanumeric(r) })
+	if len(labelValue) > validation.LabelValueMaxLength {
+		labelValue = strings.TrimRightFunc(labelValue[:validation.LabelValueMaxLength], func(r rune) bool { return !isAlphanumeric(r) })
+	}
+
+	if labelValue == "" {
+		return DefaultApplicationSnapshotTypeLabelValue
+	}
+
+	return labelValue
+}
+
+// isAlphanumeric returns true if the given rune is an ASCII letter or digit.
+func isAlphanumeric(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+}
+
+// ComponentsAsEnv returns the container image of each component as a '<PREFIX>_<COMPONENT>_IMAGE' environment variable,
+// suffixing the component part with a number when two names collide.
+func (a *ApplicationSnapshot) ComponentsAsEnv(prefix string) []corev1.EnvVar {
+	envVars := []corev1.EnvVar{}
+	usedNames := map[string]bool{}
+
+	sanitizedPrefix := ""
+	if prefix != "" {
+		sanitizedPrefix = sanitizeEnvVarName(prefix) + "_"
+	}
+
+	for _, component := range a.Spec.Components {
+		componentPart := sanitizeEnvVarName(component.Name)
+
+		name := envVarName(sanitizedPrefix + componentPart + "_IMAGE")
+		for suffix := 2; usedNames[name]; suffix++ {
+			name = envVarName(fmt.Sprintf("%s%s_%d_IMAGE", sanitizedPrefix, componentPart, suffix))
+		}
+		usedNames[name] = true
+
+		envVars = append(envVars, corev1.EnvVar{Name: name, Value: component.ContainerImage})
+	}
+
+	return envVars
+}
+
+// envVarName prefixes the given sanitized name with an underscore when it starts with a digit, since environment
+// variable names can't start with a digit.
+func envVarName(name string) string {
+	if name != "" && name[0] >= '0' && name[0] <= '9' {
+		return "_" + name
+	}
+
+	return name
+}
+
+// sanitizeEnvVarName upper-cases the given string, and replaces every character which isn't valid in an environment
+// variable name with an underscore.
+func sanitizeEnvVarName(str string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' {
+			return r
+		}
+		return '_'
+	}, strings.ToUpper(str))
+}
+
+// ComponentsCSV returns the components of the ApplicationSnapshot as CSV, with a 'name,containerImage' header line
+// followed by a row for each component, sorted by name. Fields are quoted as described in RFC 4180 when needed.
+func (a *ApplicationSnapshot) ComponentsCSV() string {
+	components := make([]ApplicationSnapshotComponent, len(a.Spec.Components))
+	copy(components, a.Spec.Components)
+	sort.SliceStable(components, func(i, j int) bool {
+		return components[i].Name < components[j].Name
+	})
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	// Writing to a bytes.Buffer can't fail, so the errors are ignored
+	_ = writer.Write([]string{"name", "containerImage"})
+	for _, component := range components {
+		_ = writer.Write([]string{component.Name, component.ContainerImage})
+	}
+	writer.Flush()
+
+	return buf.String()
+}
+
+// UnapprovedComponents returns the names of the components whose container image and repository are both not approved
+// by the given allowlist.
+func (a *ApplicationSnapshot) UnapprovedComponents(allow map[string]bool) []string {
+	unapproved := []string{}
+
+	for _, component := range a.Spec.Components {
+		if allow[component.ContainerImage] {
+			continue
+		}
+
+		if named, err := reference.ParseNormalizedNamed(component.ContainerImage); err == nil {
+			if allow[named.Name()] || allow[reference.FamiliarName(named)] {
+				continue
+			}
+		}
+
+		unapproved = append(unapproved, component.Name)
+	}
+
+	return unapproved
+}
+
+// ConditionsByTime returns a copy of the conditions of the ApplicationSnapshot, sorted by LastTransitionTime from the
+// most recent to the oldest. Conditions with the same LastTransitionTime are sorted by type.
+func (a *ApplicationSnapshot) ConditionsByTime() []metav1.Condition {
+	conditions := make([]metav1.Condition, len(a.Status.Conditions))
+	for i := range a.Status.Conditions {
+		a.Status.Conditions[i].DeepCopyInto(&conditions[i])
+	}
+
+	sort.SliceStable(conditions, func(i, j int) bool {
+		if !conditions[i].LastTransitionTime.Equal(&conditions[j].LastTransitionTime) {
+			return conditions[j].LastTransitionTime.Before(&conditions[i].LastTransitionTime)
+		}
+		return conditions[i].Type < conditions[j].Type
+	})
+
+	return conditions
+}
+
+// StaleConditions returns the types of the conditions which haven't been reconciled for the current generation of the
+// ApplicationSnapshot, that is whose ObservedGeneration is lower than its Generation, in the order of the conditions.
+func (a *ApplicationSnapshot) StaleConditions() []string {
+	stale := []string{}
+	for _, condition := range a.Status.Conditions {
+		if condition.ObservedGeneration < a.Generation {
+			stale = append(stale, condition.Type)
+		}
+	}
+
+	return stale
+}
+
+// ConditionFresh checks whether the Succeeded condition of the ApplicationSnapshot transitioned within maxAge of now
+func (a *ApplicationSnapshot) ConditionFresh(maxAge time.Duration, now time.Time) bool {
+	condition := meta.FindStatusCondition(a.Status.Conditions, applicationSnapshotConditionType)
+	if condition == nil {
+		return false
+	}
+
+	return now.Sub(condition.LastTransitionTime.Time) <= maxAge
+}
+
+// NeedsRevalidation checks whether the ApplicationSnapshot needs to be validated again, that is whether it has no
+// Validated condition, or whether this condition was observed for an older generation than the current one.
+func (a *ApplicationSnapshot) NeedsRevalidation() bool {
+	condition := meta.FindStatusCondition(a.Status.Conditions, ApplicationSnapshotConditionTypeValidated)
+	if condition == nil {
+		return true
+	}
+
+	return condition.ObservedGeneration < a.Generation
+}
+
+// HasAllConditions checks whether the ApplicationSnapshot has a condition of each of the given types, regardless of
+// their status.
+func (a *ApplicationSnapshot) HasAllConditions(types ...string) bool {
+	for _, conditionType := range types {
+		if meta.FindStatusCondition(a.Status.Conditions, conditionType) == nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// StatusOnlyJSON returns the JSON representation of the ApplicationSnapshot status, wrapped as '{"status": {...}}',
+// for use as the body of a status subresource patch. The spec and metadata are not included.
+func (a *ApplicationSnapshot) StatusOnlyJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Status ApplicationSnapshotStatus `json:"status"`
+	}{Status: a.Status})
+}
+
+// RestoreSpecPatch returns a JSON merge patch which, applied to the ApplicationSnapshot, sets its spec to the spec of
+// the target ApplicationSnapshot, for use by rollback tooling. The status and metadata are left untouched.
+func (a *ApplicationSnapshot) RestoreSpecPatch(target *ApplicationSnapshot) ([]byte, error) {
+	if target == nil {
+		return nil, fmt.Errorf("no target ApplicationSnapshot to restore the spec from")
+	}
+
+	type specOnly struct {
+		Spec ApplicationSnapshotSpec `json:"spec"`
+	}
+
+	original, err := json.Marshal(specOnly{Spec: a.Spec})
+	if err != nil {
+		return nil, err
+	}
+	modified, err := json.Marshal(specOnly{Spec: target.Spec})
+	if err != nil {
+		return nil, err
+	}
+
+	return jsonpatch.CreateMergePatch(original, modified)
+}
+
+// ConditionsEventPayload returns a compact JSON document describing the conditions of the ApplicationSnapshot, for use
+// as the data of events published to external event buses.
+func (a *ApplicationSnapshot) ConditionsEventPayload() ([]byte, error) {
+	conditions := a.Status.Conditions
+	if conditions == nil {
+		conditions = []metav1.Condition{}
+	}
+
+	return json.Marshal(struct {
+		Name        string             `json:"name"`
+		Namespace   string             `json:"namespace"`
+		Application string             `json:"application"`
+		Phase       string             `json:"phase"`
+		Conditions  []metav1.Condition `json:"conditions"`
+	}{
+		Name:        a.Name,
+		Namespace:   a.Namespace,
+		Application: a.Spec.Application,
+		Phase:       a.Phase(),
+		Conditions:  conditions,
+	})
+}
+
+// AuditEntry records a state change of an ApplicationSnapshot, for compliance audit trails.
+// +kubebuilder:object:generate=false
+type AuditEntry struct {
+	// Timestamp is the time of the state change, that is the last transition time of the Succeeded condition
+	Timestamp metav1.Time `json:"timestamp"`
+
+	// ObjectRef references the ApplicationSnapshot
+	ObjectRef corev1.ObjectReference `json:"objectRef"`
+
+	// Phase is the phase of the ApplicationSnapshot
+	Phase string `json:"phase"`
+
+	// Reason is the reason of the Succeeded condition
+	Reason string `json:"reason,omitempty"`
+
+	// Message is the message of the Succeeded condition
+	Message string `json:"message,omitempty"`
+
+	// Generation is the generation of the ApplicationSnapshot
+	Generation int64 `json:"generation"`
+}
+
+// AuditRecord returns an AuditEntry recording the current phase and Succeeded condition of the ApplicationSnapshot
+func (a *ApplicationSnapshot) AuditRecord() AuditEntry {
+	entry := AuditEntry{
+		Timestamp: metav1.Now(),
+		ObjectRef: corev1.ObjectReference{
+			APIVersion:      GroupVersion.String(),
+			Kind:            "ApplicationSnapshot",
+			Namespace:       a.Namespace,
+			Name:            a.Name,
+			UID:             a.UID,
+			ResourceVersion: a.ResourceVersion,
+		},
+		Phase:      a.Phase(),
+		Generation: a.Generation,
+	}
+
+	if condition := meta.FindStatusCondition(a.Status.Conditions, applicationSnapshotConditionType); condition != nil {
+		entry.Timestamp = condition.LastTransitionTime
+		entry.Reason = condition.Reason
+		entry.Message = condition.Message
+	}
+
+	return entry
+}
+
+// AppendAuditRecord writes the AuditRecord of the ApplicationSnapshot to the given writer as a single JSON line, so
+// that controllers can stream audit entries to a log sink.
+func (a *ApplicationSnapshot) AppendAuditRecord(w io.Writer) error {
+	line, err := json.Marshal(a.AuditRecord())
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(append(line, '\n'))
+	return err
+}
+
+// ContentSupersedes checks whether every component of the other ApplicationSnapshot is also present in this one, with a
+// non-empty image.
+func (a *ApplicationSnapshot) ContentSupersedes(other *ApplicationSnapshot) bool {
+	if other == nil {
+		return false
+	}
+
+	images := map[string]string{}
+	for _, component := range a.Spec.Components {
+		images[component.Name] = component.ContainerImage
+	}
+
+	for _, component := range other.Spec.Components {
+		if images[component.Name] == "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// applicationSnapshotSpecFieldsEqual maps the JSON names of the spec fields which can be owned by a controller to a
+// function checking whether two specs have the same value for the field
+var applicationSnapshotSpecFieldsEqual = map[string]func(a, b *ApplicationSnapshotSpec) bool{
+	"application":        func(a, b *ApplicationSnapshotSpec) bool { return a.Application == b.Application },
+	"displayName":        func(a, b *ApplicationSnapshotSpec) bool { return a.DisplayName == b.DisplayName },
+	"displayDescription": func(a, b *ApplicationSnapshotSpec) bool { return a.DisplayDescription == b.DisplayDescription },
+	"type":               func(a, b *ApplicationSnapshotSpec) bool { return a.Type == b.Type },
+	"components": func(a, b *ApplicationSnapshotSpec) bool {
+		return equality.Semantic.DeepEqual(a.Components, b.Components)
+	},
+	"artifacts": func(a, b *ApplicationSnapshotSpec) bool {
+		return equality.Semantic.DeepEqual(a.Artifacts, b.Artifacts)
+	},
+	"ttlSecondsAfterCompletion": func(a, b *ApplicationSnapshotSpec) bool {
+		return equality.Semantic.DeepEqual(a.TTLSecondsAfterCompletion, b.TTLSecondsAfterCompletion)
+	},
+}
+
+// DefaultControllerOwnedSpecFields is the default list of the spec fields, by JSON name, owned by the controllers
+// creating ApplicationSnapshots
+var DefaultControllerOwnedSpecFields = []string{"application", "components", "type"}
+
+// MatchesDesired checks whether the given spec fields of the ApplicationSnapshot, identified by their JSON name, match
+// the desired ApplicationSnapshot. When no field is given, DefaultControllerOwnedSpecFields is used.
+func (a *ApplicationSnapshot) MatchesDesired(desired *ApplicationSnapshot, ownedFields ...string) bool {
+	if desired == nil {
+		return false
+	}
+	if len(ownedFields) == 0 {
+		ownedFields = DefaultControllerOwnedSpecFields
+	}
+
+	for _, ownedField := range ownedFields {
+		equal, exists := applicationSnapshotSpecFieldsEqual[ownedField]
+		if !exists || !equal(&a.Spec, &desired.Spec) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ChangedSince checks whether the ApplicationSnapshot changed since the given resourceVersion, or the given one is empty
+func (a *ApplicationSnapshot) ChangedSince(resourceVersion string) bool {
+	return resourceVersion == "" || a.ResourceVersion != resourceVersion
+}
+
+// IsStaleRelativeTo checks whether this copy of the ApplicationSnapshot is behind the given latest copy retrieved from
+// the server, by comparing their resourceVersion, generation and condition observed generation.
+func (a *ApplicationSnapshot) IsStaleRelativeTo(latest *ApplicationSnapshot) bool {
+	if latest == nil {
+		return false
+	}
+
+	if a.ResourceVersion != "" && latest.ResourceVersion != "" && a.ResourceVersion != latest.ResourceVersion {
+		return true
+	}
+	if a.Generation < latest.Generation {
+		return true
+	}
+
+	condition, latestCondition := a.PhaseCondition(), latest.PhaseCondition()
+	if latestCondition != nil && (condition == nil || condition.ObservedGeneration < latestCondition.ObservedGeneration) {
+		return true
+	}
+
+	return false
+}
+
+// IsNoOpRelease checks whether the components of the ApplicationSnapshot are identical to the ones of the last released
+// ApplicationSnapshot, regardless of their order.
+func (a *ApplicationSnapshot) IsNoOpRelease(lastReleased *ApplicationSnapshot) bool {
+	if lastReleased == nil {
+		return false
+	}
+
+	return a.ChurnScore(lastReleased) == 0
+}
+
+// applicationSnapshotEventReasons maps the ApplicationSnapshot reasons to the reasons used for Kubernetes Events
+var applicationSnapshotEventReasons = map[ApplicationSnapshotReason]string{
+	ApplicationSnapshotReasonInitialized:     "SnapshotInitialized",
+	ApplicationSnapshotReasonValidationError: "SnapshotValidationFailed",
+	ApplicationSnapshotReasonTestsFailed:     "SnapshotTestsFailed",
+	ApplicationSnapshotReasonTestsRunning:    "SnapshotTestsRunning",
+	ApplicationSnapshotReasonSucceeded:       "SnapshotSucceeded",
+	ApplicationSnapshotReasonSkipped:         "SnapshotTestsSkipped",
+	ApplicationSnapshotReasonCancelled:       "SnapshotTestsCancelled",
+	ApplicationSnapshotReasonTimedOut:        "SnapshotTestsTimedOut",
+}
+
+// EventReasonAndMessage returns the type, reason and message of a Kubernetes Event describing the current Succeeded
+// condition of the ApplicationSnapshot.
+func (a *ApplicationSnapshot) EventReasonAndMessage() (eventType, reason, message string) {
+	condition := meta.FindStatusCondition(a.Status.Conditions, applicationSnapshotConditionType)
+	if condition == nil {
+		return corev1.EventTypeNormal, "SnapshotPending", "ApplicationSnapshot is pending"
+	}
+
+	eventType = corev1.EventTypeNormal
+	if condition.Status == metav1.ConditionFalse {
+		eventType = corev1.EventTypeWarning
+	}
+
+	reason, exists := applicationSnapshotEventReasons[ApplicationSnapshotReason(condition.Reason)]
+	if !exists {
+		// Keep only the characters allowed in a CamelCase event reason
+		reason = "Snapshot" + strings.Map(func(r rune) rune {
+			if isAlphanumeric(r) {
+				return r
+			}
+			return -1
+		}, condition.Reason)
+	}
+
+	return eventType, reason, condition.Message
+}
+
+// MergeConditionsFrom copies the conditions of the other ApplicationSnapshot which are missing from this one, or more
+// recent than the ones of the same type.
+func (a *ApplicationSnapshot) MergeConditionsFrom(other *ApplicationSnapshot) {
+	if other == nil {
+		return
+	}
+
+	for _, otherCondition := range other.Status.Conditions {
+		condition := meta.FindStatusCondition(a.Status.Conditions, otherCondition.Type)
+		if condition == nil {
+			a.Status.Conditions = append(a.Status.Conditions, *otherCondition.DeepCopy())
+		} else if condition.LastTransitionTime.Before(&otherCondition.LastTransitionTime) {
+			otherCondition.DeepCopyInto(condition)
+		}
+	}
+
+	a.SyncPhaseToStatus()
+}
+
+// Sanitize removes the duplicate status conditions of each type, keeping the most recent one, and then keeps at most
+// MaxApplicationSnapshotConditions of the most recent conditions.
+func (a *ApplicationSnapshot) Sanitize() {
+	if a.Status.Conditions == nil {
+		return
+	}
+
+	conditions := []metav1.Condition{}
+	indexes := map[string]int{}
+	for _, condition := range a.Status.Conditions {
+		if i, exists := indexes[condition.Type]; exists {
+			if !condition.LastTransitionTime.Before(&conditions[i].LastTransitionTime) {
+				conditions[i] = condition
+			}
+		} else {
+			indexes[condition.Type] = len(conditions)
+			conditions = append(conditions, condition)
+		}
+	}
+	a.Status.Conditions = conditions
+
+	if len(conditions) > MaxApplicationSnapshotConditions {
+		kept := map[string]bool{}
+		for _, condition := range a.ConditionsByTime()[:MaxApplicationSnapshotConditions] {
+			kept[condition.Type] = true
+		}
+
+		a.Status.Conditions = []metav1.Condition{}
+		for _, condition := range conditions {
+			if kept[condition.Type] {
+				a.Status.Conditions = append(a.Status.Conditions, condition)
+			}
+		}
+	}
+}
+
+// ConditionChange describes a change of the status or reason of a condition of an ApplicationSnapshot
+// +kubebuilder:object:generate=false
+type ConditionChange struct {
+	// Type is the type of the condition which changed
+	Type string
+
+	// OldStatus is the status of the condition before the change
+	OldStatus metav1.ConditionStatus
+
+	// NewStatus is the status of the condition after the change
+	NewStatus metav1.ConditionStatus
+
+	// OldReason is the reason of the condition before the change
+	OldReason string
+
+	// NewReason is the reason of the condition after the change
+	NewReason string
+}
+
+// ConditionDiff returns the conditions of the ApplicationSnapshot which were added, removed, or whose status or reason
+// changed, relative to the previous version of it.
+func (a *ApplicationSnapshot) ConditionDiff(previous *ApplicationSnapshot) []ConditionChange {
+	changes := []ConditionChange{}
+
+	previousConditions := []metav1.Condition{}
+	if previous != nil {
+		previousConditions = previous.Status.Conditions
+	}
+
+	for _, condition := range a.Status.Conditions {
+		change := ConditionChange{Type: condition.Type, NewStatus: condition.Status, NewReason: condition.Reason}
+		if previousCondition := meta.FindStatusCondition(previousConditions, condition.Type); previousCondition != nil {
+			if previousCondition.Status == condition.Status && previousCondition.Reason == condition.Reason {
+				continue
+			}
+			change.OldStatus, change.OldReason = previousCondition.Status, previousCondition.Reason
+		}
+		changes = append(changes, change)
+	}
+
+	for _, previousCondition := range previousConditions {
+		if meta.FindStatusCondition(a.Status.Conditions, previousCondition.Type) == nil {
+			changes = append(changes, ConditionChange{
+				Type:      previousCondition.Type,
+				OldStatus: previousCondition.Status,
+				OldReason: previousCondition.Reason,
+			})
+		}
+	}
+
+	return changes
+}
+
+// PromotionManifest links the components of an ApplicationSnapshot to the Environment they should be promoted to
+// +kubebuilder:object:generate=false
+type PromotionManifest struct {
+	// Application is the name of the Application the ApplicationSnapshot belongs to
+	Application string `json:"application"`
+
+	// Environment is the name of the Environment the components should be promoted to
+	Environment string `json:"environment"`
+
+	// Snapshot is the name of the ApplicationSnapshot being promoted
+	Snapshot string `json:"snapshot"`
+
+	// Components are the components being promoted, with their container images
+	Components []ApplicationSnapshotComponent `json:"components"`
+}
+
+// ToPromotionManifest returns the YAML representation of a PromotionManifest, linking the components of the
+// ApplicationSnapshot to the given Environment. An error is returned if the Environment name is not a valid DNS-1123 label.
+func (a *ApplicationSnapshot) ToPromotionManifest(environment string) ([]byte, error) {
+	if msgs := validation.IsDNS1123Label(environment); len(msgs) > 0 {
+		return nil, fmt.Errorf("invalid environment name %q: %s", environment, strings.Join(msgs, ", "))
+	}
+
+	manifest := PromotionManifest{
+		Application: a.Spec.Application,
+		Environment: environment,
+		Snapshot:    a.Name,
+		Components:  append([]ApplicationSnapshotComponent{}, a.Spec.Components...),
+	}
+
+	return yaml.Marshal(manifest)
+}
+
+// SetCondition creates a new condition with the given status and reason. Then, it sets this new condition,
+// unsetting previous conditions with the same type as necessary.
+func (a *ApplicationSnapshot) setStatusCondition(status metav1.ConditionStatus, reason ApplicationSnapshotReason) {
+	a.setStatusConditionWithMessage(status, reason, "")
+}
+
+// SetCondition creates a new condition with the given status, reason and message. Then, it sets this new condition,
+// unsetting previous conditions with the same type as necessary, and syncs the phase of the status.
+func (a *ApplicationSnapshot) setStatusConditionWithMessage(status metav1.ConditionStatus, reason ApplicationSnapshotReason, message string) {
+	if reason == "" {
+		reason = defaultReasonForStatus(status)
+	}
+	if message == "" {
+		message = fmt.Sprintf("ApplicationSnapshot condition %s is %s: %s", applicationSnapshotConditionType, status, reason)
+	}
+
+	meta.SetStatusCondition(&a.Status.Conditions, metav1.Condition{
+		Type:    applicationSnapshotConditionType,
+		Status:  status,
+		Reason:  reason.String(),
+		Message: message,
+	})
+
+	// SetStatusCondition only sets the LastTransitionTime when the status changes, so an existing condition without
+	// one would be left without it
+	condition := meta.FindStatusCondition(a.Status.Conditions, applicationSnapshotConditionType)
+	if condition.LastTransitionTime.IsZero() {
+		condition.LastTransitionTime = metav1.NewTime(time.Now())
+	}
+
+	a.SyncPhaseToStatus()
+}
+
+// defaultReasonForStatus returns the reason to use for a Succeeded condition with the given status, when none was provided.
+func defaultReasonForStatus(status metav1.ConditionStatus) ApplicationSnapshotReason {
+	switch status {
+	case metav1.ConditionTrue:
+		return ApplicationSnapshotReasonSucceeded
+	case metav1.ConditionFalse:
+		return ApplicationSnapshotReasonTestsFailed
+	default:
+		return ApplicationSnapshotReasonTestsRunning
+	}
+}
+
+//+kubebuilder:object:root=true
+
+// ApplicationSnapshotList contains a list of ApplicationSnapshot
+type ApplicationSnapshotList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ApplicationSnapshot `json:"items"`
+}
+
+const (
+	// AgeBucketHourBoundary is the age below which an ApplicationSnapshot is counted in the AgeBucketLessThanHour bucket
+	AgeBucketHourBoundary = time.Hour
+
+	// AgeBucketDayBoundary is the age above which an ApplicationSnapshot is counted in the AgeBucketMoreThanDay bucket
+	AgeBucketDayBoundary = 24 * time.Hour
+
+	// AgeBucketLessThanHour is the age bucket of the ApplicationSnapshots created less than an hour ago
+	AgeBucketLessThanHour = "<1h"
+
+	// AgeBucketHourToDay is the age bucket of the ApplicationSnapshots created between an hour and a day ago
+	AgeBucketHourToDay = "1-24h"
+
+	// AgeBucketMoreThanDay is the age bucket of the ApplicationSnapshots created more than a day ago
+	AgeBucketMoreThanDay = ">24h"
+)
+
+// AgeBuckets returns the number of ApplicationSnapshots of the list in each age bucket, based on the time elapsed
+// between their creation and the given time.
+func (l *ApplicationSnapshotList) AgeBuckets(now time.Time) map[string]int {
+	buckets := map[string]int{
+		AgeBucketLessThanHour: 0,
+		AgeBucketHourToDay:    0,
+		AgeBucketMoreThanDay:  0,
+	}
+
+	for _, item := range l.Items {
+		age := now.Sub(item.CreationTimestamp.Time)
+
+		switch {
+		case age < AgeBucketHourBoundary:
+			buckets[AgeBucketLessThanHour]++
+		case age <= AgeBucketDayBoundary:
+			buckets[AgeBucketHourToDay]++
+		default:
+			buckets[AgeBucketMoreThanDay]++
+		}
+	}
+
+	return buckets
+}
+
+// ReconcileRequests returns a reconcile request for each ApplicationSnapshot of the list.
+func (l *ApplicationSnapshotList) ReconcileRequests() []reconcile.Request {
+	requests := make([]reconcile.Request, 0, len(l.Items))
+	for i := range l.Items {
+		requests = append(requests, l.Items[i].ReconcileRequest())
+	}
+
+	return requests
+}
+
+// UnionComponents returns, for each component name found in the ApplicationSnapshots of the list, the sorted distinct
+// container images this component has across the ApplicationSnapshots.
+func (l *ApplicationSnapshotList) UnionComponents() map[string][]string {
+	seen := map[string]map[string]bool{}
+	for _, item := range l.Items {
+		for _, component := range item.Spec.Components {
+			if seen[component.Name] == nil {
+				seen[component.Name] = map[string]bool{}
+			}
+			seen[component.Name][component.ContainerImage] = true
+		}
+	}
+
+	union := map[string][]string{}
+	for name, images := range seen {
+		for image := range images {
+			union[name] = append(union[name], image)
+		}
+		sort.Strings(union[name])
+	}
+
+	return union
+}
+
+// Orphaned returns the ApplicationSnapshots of the list which do not reference an Application.
+func (l *ApplicationSnapshotList) Orphaned() []ApplicationSnapshot {
+	orphaned := []ApplicationSnapshot{}
+
+	for _, item := range l.Items {
+		if item.IsOrphaned() {
+			orphaned = append(orphaned, item)
+		}
+	}
+
+	return orphaned
+}
+
+// WithPhase returns the ApplicationSnapshots of the list whose Phase is one of the given phases, in the order of the
+// list. All the ApplicationSnapshots are returned when no phase is given.
+func (l *ApplicationSnapshotList) WithPhase(phases ...string) []ApplicationSnapshot {
+	if len(phases) == 0 {
+		return append([]ApplicationSnapshot{}, l.Items...)
+	}
+
+	filtered := []ApplicationSnapshot{}
+	for _, item := range l.Items {
+		if contains(phases, item.Phase()) {
+			filtered = append(filtered, item)
+		}
+	}
+
+	return filtered
+}
+
+// Collectible returns the done ApplicationSnapshots of the list which are expired or, when keepLatest is positive,
+// beyond the newest keepLatest of their application. The newest keepLatest of each application are always retained.
+func (l *ApplicationSnapshotList) Collectible(now time.Time, keepLatest int) []ApplicationSnapshot {
+	doneByApplication := map[string][]int{}
+	for i := range l.Items {
+		if l.Items[i].IsDone() {
+			key := l.Items[i].Namespace + "/" + l.Items[i].Spec.Application
+			doneByApplication[key] = append(doneByApplication[key], i)
+		}
+	}
+
+	collectible := map[int]bool{}
+	for _, indexes := range doneByApplication {
+		sort.SliceStable(indexes, func(i, j int) bool {
+			return l.Items[indexes[j]].CreationTimestamp.Before(&l.Items[indexes[i]].CreationTimestamp)
+		})
+
+		for rank, index := range indexes {
+			if rank < keepLatest {
+				continue
+			}
+			collectible[index] = l.Items[index].IsExpired(now) || (keepLatest > 0 && rank >= keepLatest)
+		}
+	}
+
+	result := []ApplicationSnapshot{}
+	for i := range l.Items {
+		if collectible[i] {
+			result = append(result, l.Items[i])
+		}
+	}
+
+	return result
+}
+
+// MarkAllFailed marks each ApplicationSnapshot of the list which is not done yet as failed, with the provided
+// reason and message. It returns the number of ApplicationSnapshots whose Succeeded condition was changed.
+func (l *ApplicationSnapshotList) MarkAllFailed(reason ApplicationSnapshotReason, message string) int {
+	transitioned := 0
+
+	for i := range l.Items {
+		if l.Items[i].IsDone() {
+			continue
+		}
+
+		previous := meta.FindStatusCondition(l.Items[i].Status.Conditions, applicationSnapshotConditionType).DeepCopy()
+		l.Items[i].MarkFailed(reason, message)
+		current := meta.FindStatusCondition(l.Items[i].Status.Conditions, applicationSnapshotConditionType)
+		if !equality.Semantic.DeepEqual(previous, current) {
+			transitioned++
+		}
+	}
+
+	return transitioned
+}
+
+// CancelSuperseded marks the ApplicationSnapshots of the list created before the latest one of the same application,
+// and not done yet, as Cancelled, and returns the number of ApplicationSnapshots cancelled.
+func (l *ApplicationSnapshotList) CancelSuperseded(latest *ApplicationSnapshot) int {
+	if latest == nil {
+		return 0
+	}
+
+	nn := latest.Namespace + "/" + latest.Name
+	cancelled := 0
+
+	for i := range l.Items {
+		item := &l.Items[i]
+		if item.Namespace != latest.Namespace || item.Name == latest.Name || item.Spec.Application != latest.Spec.Application ||
+			!createdBefore(item, latest) || item.IsDone() {
+			continue
+		}
+
+		item.MarkFailed(ApplicationSnapshotReasonCancelled, fmt.Sprintf("superseded by ApplicationSnapshot %s", nn))
+		_ = item.SetSupersededBy(nn)
+		cancelled++
+	}
+
+	return cancelled
+}
+
+// createdBefore checks whether the ApplicationSnapshot a was created before b, ordering ApplicationSnapshots created
+// in the same second by name.
+func createdBefore(a, b *ApplicationSnapshot) bool {
+	if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+		return a.CreationTimestamp.Before(&b.CreationTimestamp)
+	}
+
+	return a.Name < b.Name
+}
+
+// RequiredRBACRules returns the RBAC rules a controller processing ApplicationSnapshots requires
+func RequiredRBACRules() []rbacv1.PolicyRule {
+	return []rbacv1.PolicyRule{
+		{
+			APIGroups: []string{GroupVersion.Group},
+			Resources: []string{"applicationsnapshots"},
+			Verbs:     []string{"get", "list", "watch", "update", "patch"},
+		},
+		{
+			APIGroups: []string{GroupVersion.Group},
+			Resources: []string{"applicationsnapshots/status"},
+			Verbs:     []string{"get", "update", "patch"},
+		},
+	}
+}
+
+// SnapshotEventType is the type of a change of an ApplicationSnapshot between two ApplicationSnapshotLists
+type SnapshotEventType string
+
+const (
+	// SnapshotEventAdded is the type of the event emitted for an ApplicationSnapshot which was added
+	SnapshotEventAdded SnapshotEventType = "Added"
+
+	// SnapshotEventModified is the type of the event emitted for an ApplicationSnapshot which was modified
+	SnapshotEventModified SnapshotEventType = "Modified"
+
+	// SnapshotEventDeleted is the type of the event emitted for an ApplicationSnapshot which was deleted
+	SnapshotEventDeleted SnapshotEventType = "Deleted"
+)
+
+// SnapshotEvent describes a change of an ApplicationSnapshot between two ApplicationSnapshotLists.
+// +kubebuilder:object:generate=false
+type SnapshotEvent struct {
+	// Type is the type of the change
+	Type SnapshotEventType
+
+	// Object is the ApplicationSnapshot after the change, or before it was deleted
+	Object ApplicationSnapshot
+}
+
+// Events returns the Added, Modified and Deleted events describing the changes between the previous
+// ApplicationSnapshotList and this one.
+func (l *ApplicationSnapshotList) Events(previous *ApplicationSnapshotList) []SnapshotEvent {
+	events := []SnapshotEvent{}
+
+	previousItems := map[types.NamespacedName]*ApplicationSnapshot{}
+	if previous != nil {
+		for i := range previous.Items {
+			item := &previous.Items[i]
+			previousItems[types.NamespacedName{Namespace: item.Namespace, Name: item.Name}] = item
+		}
+	}
+
+	seen := map[types.NamespacedName]bool{}
+	for _, item := range l.Items {
+		key := types.NamespacedName{Namespace: item.Namespace, Name: item.Name}
+		seen[key] = true
+
+		previousItem, exists := previousItems[key]
+		switch {
+		case !exists:
+			events = append(events, SnapshotEvent{Type: SnapshotEventAdded, Object: *item.DeepCopy()})
+		case isModified(previousItem, &item):
+			events = append(events, SnapshotEvent{Type: SnapshotEventModified, Object: *item.DeepCopy()})
+		}
+	}
+
+	if previous != nil {
+		for _, item := range previous.Items {
+			if !seen[types.NamespacedName{Namespace: item.Namespace, Name: item.Name}] {
+				events = append(events, SnapshotEvent{Type: SnapshotEventDeleted, Object: *item.DeepCopy()})
+			}
+		}
+	}
+
+	return events
+}
+
+// isModified checks whether the ApplicationSnapshot was modified, comparing the resourceVersions when they are set and
+// the ApplicationSnapshots themselves otherwise.
+func isModified(previous, current *ApplicationSnapshot) bool {
+	if previous.ResourceVersion != "" || current.ResourceVersion != "" {
+		return previous.ResourceVersion != current.ResourceVersion
+	}
+
+	return !equality.Semantic.DeepEqual(previous, current)
 }
 
 func init() {
@@ -0,0 +1,2958 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/distribution/reference"
+	jsonpatch "github.com/evanphx/json-patch"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/yaml"
+)
+
+var _ = Describe("ApplicationSnapshot type tests", func() {
+
+	var snapshot *ApplicationSnapshot
+
+	BeforeEach(func() {
+		snapshot = &ApplicationSnapshot{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "my-snapshot",
+				Namespace: "my-namespace",
+			},
+			Spec: ApplicationSnapshotSpec{
+				Application: "my-app",
+				Components: []ApplicationSnapshotComponent{
+					{Name: "component-a", ContainerImage: "quay.io/org/component-a:v1"},
+					{Name: "component-b", ContainerImage: "quay.io/org/component-b:v1"},
+				},
+			},
+		}
+	})
+
+	Context("Testing HasSucceeded", func() {
+
+		It("should have succeeded when the Succeeded condition is True", func() {
+			snapshot.Status.Conditions = []metav1.Condition{
+				{Type: ApplicationSnapshotConditionTypeSucceeded, Status: metav1.ConditionTrue, Reason: ApplicationSnapshotReasonSucceeded.String()},
+			}
+			Expect(snapshot.HasSucceeded()).To(BeTrue())
+		})
+
+		It("should not have succeeded when the Succeeded condition is False", func() {
+			snapshot.Status.Conditions = []metav1.Condition{
+				{Type: ApplicationSnapshotConditionTypeSucceeded, Status: metav1.ConditionFalse, Reason: ApplicationSnapshotReasonTestsFailed.String()},
+			}
+			Expect(snapshot.HasSucceeded()).To(BeFalse())
+		})
+
+		It("should not have succeeded while running or without a Succeeded condition", func() {
+			Expect(snapshot.HasSucceeded()).To(BeFalse())
+
+			snapshot.MarkRunning()
+			Expect(snapshot.HasSucceeded()).To(BeFalse())
+		})
+	})
+
+	Context("Testing MarkSkipped", func() {
+
+		It("should mark the snapshot as done and succeeded with the Skipped reason", func() {
+			snapshot.MarkRunning()
+			Expect(snapshot.IsSkipped()).To(BeFalse())
+
+			snapshot.MarkSkipped("tests are not required by policy")
+
+			Expect(snapshot.Status.CompletionTime).ToNot(BeNil())
+			Expect(snapshot.IsDone()).To(BeTrue())
+			Expect(snapshot.IsSkipped()).To(BeTrue())
+			Expect(snapshot.HasSucceeded()).To(BeTrue())
+
+			condition := meta.FindStatusCondition(snapshot.Status.Conditions, applicationSnapshotConditionType)
+			Expect(condition).ToNot(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			Expect(condition.Reason).To(Equal(ApplicationSnapshotReasonSkipped.String()))
+			Expect(condition.Message).To(Equal("tests are not required by policy"))
+		})
+
+		It("should not override a snapshot which is already done", func() {
+			snapshot.MarkFailed(ApplicationSnapshotReasonTestsFailed, "tests failed")
+			snapshot.MarkSkipped("tests are not required by policy")
+
+			Expect(snapshot.IsSkipped()).To(BeFalse())
+			Expect(snapshot.HasSucceeded()).To(BeFalse())
+		})
+
+		It("should not report a succeeded snapshot as skipped", func() {
+			snapshot.MarkSucceeded()
+
+			Expect(snapshot.HasSucceeded()).To(BeTrue())
+			Expect(snapshot.IsSkipped()).To(BeFalse())
+		})
+	})
+
+	Context("Testing ParsedComponentImages", func() {
+
+		It("should parse all the component images when they are valid", func() {
+			snapshot.Spec.Components = append(snapshot.Spec.Components, ApplicationSnapshotComponent{
+				Name:           "component-c",
+				ContainerImage: "quay.io/org/component-c@sha256:" + strings.Repeat("a", 64),
+			})
+
+			parsedImages, err := snapshot.ParsedComponentImages()
+			Expect(err).To(BeNil())
+			Expect(parsedImages).To(HaveLen(3))
+			Expect(parsedImages["component-a"].Name()).To(Equal("quay.io/org/component-a"))
+
+			tagged, isTagged := parsedImages["component-b"].(reference.Tagged)
+			Expect(isTagged).To(BeTrue())
+			Expect(tagged.Tag()).To(Equal("v1"))
+
+			_, isDigested := parsedImages["component-c"].(reference.Digested)
+			Expect(isDigested).To(BeTrue())
+		})
+
+		It("should return an error identifying the component whose image is invalid", func() {
+			snapshot.Spec.Components[1].ContainerImage = "quay.io/org/Component-B:v1"
+
+			parsedImages, err := snapshot.ParsedComponentImages()
+			Expect(err).ToNot(BeNil())
+			Expect(parsedImages).To(BeNil())
+			Expect(err.Error()).To(ContainSubstring("component-b"))
+			Expect(err.Error()).ToNot(ContainSubstring("component-a"))
+		})
+	})
+
+	Context("Testing TrySetStatusOwner and ReleaseStatusOwner", func() {
+
+		It("should acquire the status when it is not owned", func() {
+			Expect(snapshot.TrySetStatusOwner("controller-a")).To(BeTrue())
+			Expect(snapshot.Annotations).To(HaveKeyWithValue(ApplicationSnapshotStatusOwnerAnnotation, "controller-a"))
+		})
+
+		It("should allow the same owner to re-acquire the status", func() {
+			Expect(snapshot.TrySetStatusOwner("controller-a")).To(BeTrue())
+			Expect(snapshot.TrySetStatusOwner("controller-a")).To(BeTrue())
+			Expect(snapshot.Annotations).To(HaveKeyWithValue(ApplicationSnapshotStatusOwnerAnnotation, "controller-a"))
+		})
+
+		It("should not allow a different owner to acquire the status while it is held", func() {
+			Expect(snapshot.TrySetStatusOwner("controller-a")).To(BeTrue())
+			Expect(snapshot.TrySetStatusOwner("controller-b")).To(BeFalse())
+			Expect(snapshot.Annotations).To(HaveKeyWithValue(ApplicationSnapshotStatusOwnerAnnotation, "controller-a"))
+
+			By("ignoring a release from an owner that does not hold the status")
+			snapshot.ReleaseStatusOwner("controller-b")
+			Expect(snapshot.Annotations).To(HaveKeyWithValue(ApplicationSnapshotStatusOwnerAnnotation, "controller-a"))
+
+			By("allowing the other owner to acquire the status once it has been released")
+			snapshot.ReleaseStatusOwner("controller-a")
+			Expect(snapshot.Annotations).ToNot(HaveKey(ApplicationSnapshotStatusOwnerAnnotation))
+			Expect(snapshot.TrySetStatusOwner("controller-b")).To(BeTrue())
+		})
+	})
+
+	Context("Testing AllConditionTypes", func() {
+
+		It("should include the Succeeded condition type and contain no duplicates", func() {
+			conditionTypes := AllConditionTypes()
+			Expect(conditionTypes).To(ContainElement(ApplicationSnapshotConditionTypeSucceeded))
+
+			seen := map[string]bool{}
+			for _, conditionType := range conditionTypes {
+				Expect(seen).ToNot(HaveKey(conditionType))
+				seen[conditionType] = true
+			}
+		})
+	})
+
+	Context("Testing StaleComponents", func() {
+
+		It("should return no components when the snapshot is fully fresh", func() {
+			Expect(snapshot.StaleComponents(map[string]string{
+				"component-a": "quay.io/org/component-a:v1",
+				"component-b": "quay.io/org/component-b:v1",
+				"component-c": "quay.io/org/component-c:v1",
+			})).To(BeEmpty())
+		})
+
+		It("should return only the components whose image differs when the snapshot is partially stale", func() {
+			Expect(snapshot.StaleComponents(map[string]string{
+				"component-a": "quay.io/org/component-a:v1",
+				"component-b": "quay.io/org/component-b:v2",
+			})).To(Equal([]string{"component-b"}))
+		})
+
+		It("should return all the components when the snapshot is fully stale", func() {
+			Expect(snapshot.StaleComponents(map[string]string{
+				"component-a": "quay.io/org/component-a:v2",
+				"component-b": "quay.io/org/component-b:v2",
+			})).To(Equal([]string{"component-a", "component-b"}))
+		})
+
+		It("should skip components which are absent from the current images", func() {
+			Expect(snapshot.StaleComponents(map[string]string{
+				"component-b": "quay.io/org/component-b:v2",
+			})).To(Equal([]string{"component-b"}))
+		})
+	})
+
+	Context("Testing the Succeeded condition reason and message", func() {
+
+		expectPopulatedCondition := func(snapshot *ApplicationSnapshot) {
+			condition := meta.FindStatusCondition(snapshot.Status.Conditions, applicationSnapshotConditionType)
+			Expect(condition).ToNot(BeNil())
+			Expect(condition.Reason).ToNot(BeEmpty())
+			Expect(condition.Message).ToNot(BeEmpty())
+		}
+
+		It("should never leave the reason or message empty after any Mark* call", func() {
+			marks := map[string]func(*ApplicationSnapshot){
+				"MarkRunning":   func(s *ApplicationSnapshot) { s.MarkRunning() },
+				"MarkSucceeded": func(s *ApplicationSnapshot) { s.MarkSucceeded() },
+				"MarkSkipped":   func(s *ApplicationSnapshot) { s.MarkSkipped("") },
+				"MarkFailed":    func(s *ApplicationSnapshot) { s.MarkFailed("", "") },
+				"MarkInvalid":   func(s *ApplicationSnapshot) { s.MarkInvalid("", "") },
+			}
+
+			for name, mark := range marks {
+				By("calling " + name)
+				current := snapshot.DeepCopy()
+				mark(current)
+				expectPopulatedCondition(current)
+			}
+		})
+
+		It("should default the reason of MarkFailed to TestsFailed, and the one of MarkInvalid to Error", func() {
+			failed := snapshot.DeepCopy()
+			failed.MarkRunning()
+			failed.MarkFailed("", "")
+			condition := meta.FindStatusCondition(failed.Status.Conditions, applicationSnapshotConditionType)
+			Expect(condition.Reason).To(Equal(ApplicationSnapshotReasonTestsFailed.String()))
+
+			invalid := snapshot.DeepCopy()
+			invalid.MarkInvalid("", "")
+			condition = meta.FindStatusCondition(invalid.Status.Conditions, applicationSnapshotConditionType)
+			Expect(condition.Reason).To(Equal(ApplicationSnapshotReasonValidationError.String()))
+		})
+
+		It("should have a health check expression requiring a True Succeeded condition", func() {
+			Expect(snapshot.HealthCheckExpression()).To(Equal(
+				"status.conditions.exists(c, c.type == 'Succeeded' && c.status == 'True')"))
+		})
+	})
+
+	Context("Testing ApplicationSnapshotList MarkAllFailed", func() {
+
+		It("should only fail the snapshots which are not done yet, and return their count", func() {
+			running := snapshot.DeepCopy()
+			running.MarkRunning()
+
+			notStarted := snapshot.DeepCopy()
+
+			succeeded := snapshot.DeepCopy()
+			succeeded.MarkSucceeded()
+
+			failed := snapshot.DeepCopy()
+			failed.MarkFailed(ApplicationSnapshotReasonTestsFailed, "tests failed")
+
+			list := &ApplicationSnapshotList{
+				Items: []ApplicationSnapshot{*running, *notStarted, *succeeded, *failed},
+			}
+
+			Expect(list.MarkAllFailed(ApplicationSnapshotReasonValidationError, "registry outage")).To(Equal(2))
+
+			for i, expectedReason := range []ApplicationSnapshotReason{
+				ApplicationSnapshotReasonValidationError,
+				ApplicationSnapshotReasonValidationError,
+				ApplicationSnapshotReasonSucceeded,
+				ApplicationSnapshotReasonTestsFailed,
+			} {
+				condition := meta.FindStatusCondition(list.Items[i].Status.Conditions, applicationSnapshotConditionType)
+				Expect(condition).ToNot(BeNil())
+				Expect(condition.Reason).To(Equal(expectedReason.String()))
+			}
+		})
+
+		It("should not count the snapshots with a terminal reason and no completion time, which are left unchanged", func() {
+			timedOut := snapshot.DeepCopy()
+			timedOut.Status.Conditions = []metav1.Condition{{
+				Type:   ApplicationSnapshotConditionTypeSucceeded,
+				Status: metav1.ConditionUnknown,
+				Reason: ApplicationSnapshotReasonTimedOut.String(),
+			}}
+
+			running := snapshot.DeepCopy()
+			running.MarkRunning()
+
+			list := &ApplicationSnapshotList{Items: []ApplicationSnapshot{*timedOut, *running}}
+
+			Expect(list.MarkAllFailed(ApplicationSnapshotReasonTestsFailed, "registry outage")).To(Equal(1))
+			Expect(list.Items[0].Status.CompletionTime).To(BeNil())
+			Expect(list.Items[0].Status.Conditions).To(Equal(timedOut.Status.Conditions))
+		})
+	})
+
+	Context("Testing ValidateArtifacts", func() {
+
+		It("should accept snapshots without artifacts", func() {
+			Expect(snapshot.ValidateArtifacts()).To(Succeed())
+		})
+
+		It("should accept small, valid JSON", func() {
+			snapshot.Spec.Artifacts.UnstableFields = &apiextensionsv1.JSON{Raw: []byte(`{"commit":"abc123"}`)}
+			Expect(snapshot.ValidateArtifacts()).To(Succeed())
+		})
+
+		It("should reject invalid JSON", func() {
+			snapshot.Spec.Artifacts.UnstableFields = &apiextensionsv1.JSON{Raw: []byte(`{"commit":`)}
+
+			err := snapshot.ValidateArtifacts()
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(ContainSubstring("must be valid JSON"))
+		})
+
+		It("should reject an oversized payload", func() {
+			payload := `{"data":"` + strings.Repeat("a", MaxArtifactsUnstableFieldsSize) + `"}`
+			snapshot.Spec.Artifacts.UnstableFields = &apiextensionsv1.JSON{Raw: []byte(payload)}
+
+			err := snapshot.ValidateArtifacts()
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(ContainSubstring("spec.artifacts.unstableFields"))
+		})
+	})
+
+	Context("Testing ChurnScore", func() {
+
+		It("should return the number of components when there is no baseline", func() {
+			Expect(snapshot.ChurnScore(nil)).To(Equal(2))
+		})
+
+		It("should return zero when the snapshot is identical to the baseline", func() {
+			Expect(snapshot.ChurnScore(snapshot.DeepCopy())).To(Equal(0))
+		})
+
+		It("should count added, removed and updated components", func() {
+			baseline := snapshot.DeepCopy()
+
+			By("adding a component")
+			snapshot.Spec.Components = append(snapshot.Spec.Components,
+				ApplicationSnapshotComponent{Name: "component-c", ContainerImage: "quay.io/org/component-c:v1"})
+			Expect(snapshot.ChurnScore(baseline)).To(Equal(1))
+
+			By("updating a component")
+			snapshot.Spec.Components[0].ContainerImage = "quay.io/org/component-a:v2"
+			Expect(snapshot.ChurnScore(baseline)).To(Equal(2))
+
+			By("removing a component")
+			snapshot.Spec.Components = snapshot.Spec.Components[:1]
+			snapshot.Spec.Components = append(snapshot.Spec.Components,
+				ApplicationSnapshotComponent{Name: "component-c", ContainerImage: "quay.io/org/component-c:v1"})
+			Expect(snapshot.ChurnScore(baseline)).To(Equal(3))
+		})
+	})
+
+	Context("Testing IsOrphaned and ApplicationSnapshotList Orphaned", func() {
+
+		It("should not report a snapshot with an application as orphaned", func() {
+			Expect(snapshot.IsOrphaned()).To(BeFalse())
+		})
+
+		It("should report a snapshot without an application as orphaned", func() {
+			snapshot.Spec.Application = ""
+			Expect(snapshot.IsOrphaned()).To(BeTrue())
+		})
+
+		It("should only return the orphaned snapshots of a list", func() {
+			orphaned := snapshot.DeepCopy()
+			orphaned.Name = "orphaned-snapshot"
+			orphaned.Spec.Application = ""
+
+			list := &ApplicationSnapshotList{Items: []ApplicationSnapshot{*snapshot, *orphaned}}
+
+			result := list.Orphaned()
+			Expect(result).To(HaveLen(1))
+			Expect(result[0].Name).To(Equal("orphaned-snapshot"))
+		})
+	})
+
+	Context("Testing SetBuildPipelineRun and GetBuildPipelineRun", func() {
+
+		It("should round-trip the build PipelineRun of a component", func() {
+			_, exists := snapshot.GetBuildPipelineRun("component-a")
+			Expect(exists).To(BeFalse())
+
+			Expect(snapshot.SetBuildPipelineRun("component-a", "build-namespace/component-a-build-1")).To(Succeed())
+
+			nn, exists := snapshot.GetBuildPipelineRun("component-a")
+			Expect(exists).To(BeTrue())
+			Expect(nn).To(Equal("build-namespace/component-a-build-1"))
+
+			By("ensuring the build PipelineRuns are deep copied")
+			copied := snapshot.DeepCopy()
+			Expect(copied.SetBuildPipelineRun("component-a", "build-namespace/component-a-build-2")).To(Succeed())
+			nn, _ = snapshot.GetBuildPipelineRun("component-a")
+			Expect(nn).To(Equal("build-namespace/component-a-build-1"))
+		})
+
+		It("should reject a build PipelineRun which is not a namespaced name", func() {
+			for _, nn := range []string{"", "component-a-build-1", "build-namespace/", "Build-Namespace/component-a-build-1", "a/b/c"} {
+				Expect(snapshot.SetBuildPipelineRun("component-a", nn)).ToNot(Succeed())
+			}
+
+			_, exists := snapshot.GetBuildPipelineRun("component-a")
+			Expect(exists).To(BeFalse())
+		})
+	})
+
+	Context("Testing AutoReleaseEligible", func() {
+
+		digest := "@sha256:" + strings.Repeat("a", 64)
+
+		BeforeEach(func() {
+			snapshot.CreationTimestamp = metav1.Now()
+			snapshot.Annotations = map[string]string{ApplicationSnapshotApprovedAnnotation: "true"}
+			snapshot.Spec.Components[0].ContainerImage = "quay.io/org/component-a" + digest
+			snapshot.Spec.Components[1].ContainerImage = "quay.io/org/component-b:v1" + digest
+			snapshot.MarkSucceeded()
+		})
+
+		It("should be eligible when every policy requirement is met", func() {
+			eligible, reason := snapshot.AutoReleaseEligible(AutoReleasePolicy{
+				RequireDigests:  true,
+				RequireApproval: true,
+				MaxAge:          time.Hour,
+			})
+			Expect(eligible).To(BeTrue())
+			Expect(reason).To(BeEmpty())
+		})
+
+		It("should not be eligible when the snapshot has not succeeded", func() {
+			failed := snapshot.DeepCopy()
+			failed.Status = ApplicationSnapshotStatus{}
+			failed.MarkFailed(ApplicationSnapshotReasonTestsFailed, "tests failed")
+
+			eligible, reason := failed.AutoReleaseEligible(AutoReleasePolicy{})
+			Expect(eligible).To(BeFalse())
+			Expect(reason).To(Equal("the snapshot has not succeeded"))
+		})
+
+		It("should not be eligible when digests are required and a component is not pinned by digest", func() {
+			snapshot.Spec.Components[1].ContainerImage = "quay.io/org/component-b:v1"
+
+			eligible, reason := snapshot.AutoReleaseEligible(AutoReleasePolicy{RequireDigests: true})
+			Expect(eligible).To(BeFalse())
+			Expect(reason).To(Equal(`the container image of component "component-b" is not pinned by digest`))
+
+			eligible, _ = snapshot.AutoReleaseEligible(AutoReleasePolicy{})
+			Expect(eligible).To(BeTrue())
+		})
+
+		It("should not be eligible when approval is required and the snapshot is not approved", func() {
+			delete(snapshot.Annotations, ApplicationSnapshotApprovedAnnotation)
+
+			eligible, reason := snapshot.AutoReleaseEligible(AutoReleasePolicy{RequireApproval: true})
+			Expect(eligible).To(BeFalse())
+			Expect(reason).To(Equal("the snapshot has not been approved"))
+
+			eligible, _ = snapshot.AutoReleaseEligible(AutoReleasePolicy{})
+			Expect(eligible).To(BeTrue())
+		})
+
+		It("should not be eligible when the snapshot is older than the maximum age", func() {
+			snapshot.CreationTimestamp = metav1.NewTime(time.Now().Add(-2 * time.Hour))
+
+			eligible, reason := snapshot.AutoReleaseEligible(AutoReleasePolicy{MaxAge: time.Hour})
+			Expect(eligible).To(BeFalse())
+			Expect(reason).To(Equal("the snapshot is older than the maximum age of 1h0m0s"))
+
+			eligible, _ = snapshot.AutoReleaseEligible(AutoReleasePolicy{})
+			Expect(eligible).To(BeTrue())
+		})
+	})
+
+	Context("Testing ComponentsAsEnv", func() {
+
+		It("should upper-case the component names and replace hyphens with underscores", func() {
+			Expect(snapshot.ComponentsAsEnv("snapshot")).To(Equal([]corev1.EnvVar{
+				{Name: "SNAPSHOT_COMPONENT_A_IMAGE", Value: "quay.io/org/component-a:v1"},
+				{Name: "SNAPSHOT_COMPONENT_B_IMAGE", Value: "quay.io/org/component-b:v1"},
+			}))
+		})
+
+		It("should omit the prefix when it is empty", func() {
+			envVars := snapshot.ComponentsAsEnv("")
+			Expect(envVars[0].Name).To(Equal("COMPONENT_A_IMAGE"))
+		})
+
+		It("should add a suffix when two component names sanitize identically", func() {
+			snapshot.Spec.Components = append(snapshot.Spec.Components,
+				ApplicationSnapshotComponent{Name: "component.a", ContainerImage: "quay.io/org/component-a:v2"})
+
+			Expect(snapshot.ComponentsAsEnv("snapshot")).To(Equal([]corev1.EnvVar{
+				{Name: "SNAPSHOT_COMPONENT_A_IMAGE", Value: "quay.io/org/component-a:v1"},
+				{Name: "SNAPSHOT_COMPONENT_B_IMAGE", Value: "quay.io/org/component-b:v1"},
+				{Name: "SNAPSHOT_COMPONENT_A_2_IMAGE", Value: "quay.io/org/component-a:v2"},
+			}))
+		})
+
+		It("should add a suffix when two component names collide once escaped", func() {
+			snapshot.Spec.Components = []ApplicationSnapshotComponent{
+				{Name: "1a", ContainerImage: "quay.io/org/1a:v1"},
+				{Name: "_1a", ContainerImage: "quay.io/org/_1a:v1"},
+			}
+
+			Expect(snapshot.ComponentsAsEnv("")).To(Equal([]corev1.EnvVar{
+				{Name: "_1A_IMAGE", Value: "quay.io/org/1a:v1"},
+				{Name: "_1A_2_IMAGE", Value: "quay.io/org/_1a:v1"},
+			}))
+		})
+
+		It("should escape a prefix starting with a digit", func() {
+			snapshot.Spec.Components = []ApplicationSnapshotComponent{
+				{Name: "1a", ContainerImage: "quay.io/org/1a:v1"},
+			}
+
+			Expect(snapshot.ComponentsAsEnv("9p")).To(Equal([]corev1.EnvVar{
+				{Name: "_9P_1A_IMAGE", Value: "quay.io/org/1a:v1"},
+			}))
+		})
+	})
+
+	Context("Testing TypeLabelValue", func() {
+
+		It("should return the type unchanged when it is already a valid label value", func() {
+			snapshot.Spec.Type = ApplicationSnapshotTypeComposite
+			Expect(snapshot.TypeLabelValue()).To(Equal("composite"))
+		})
+
+		It("should return the default value when the type is empty", func() {
+			Expect(snapshot.TypeLabelValue()).To(Equal(DefaultApplicationSnapshotTypeLabelValue))
+
+			snapshot.Spec.Type = "//"
+			Expect(snapshot.TypeLabelValue()).To(Equal(DefaultApplicationSnapshotTypeLabelValue))
+		})
+
+		It("should sanitize the type into a valid label value", func() {
+			for snapshotType, expected := range map[string]string{
+				"my type/v1":             "my-type-v1",
+				"-leading.and_trailing-": "leading.and_trailing",
+				"Image Build (manual)":   "Image-Build--manual",
+			} {
+				snapshot.Spec.Type = snapshotType
+				Expect(snapshot.TypeLabelValue()).To(Equal(expected))
+				Expect(validation.IsValidLabelValue(snapshot.TypeLabelValue())).To(BeEmpty())
+			}
+		})
+
+		It("should truncate the type to the maximum label value length", func() {
+			snapshot.Spec.Type = strings.Repeat("a", 62) + "-b"
+
+			labelValue := snapshot.TypeLabelValue()
+			Expect(labelValue).To(Equal(strings.Repeat("a", 62)))
+			Expect(validation.IsValidLabelValue(labelValue)).To(BeEmpty())
+		})
+	})
+
+	Context("Testing UnapprovedComponents", func() {
+
+		It("should return no components when every repository is approved", func() {
+			Expect(snapshot.UnapprovedComponents(map[string]bool{
+				"quay.io/org/component-a": true,
+				"quay.io/org/component-b": true,
+			})).To(BeEmpty())
+		})
+
+		It("should return the components whose repository is not approved", func() {
+			Expect(snapshot.UnapprovedComponents(map[string]bool{
+				"quay.io/org/component-a": true,
+				"quay.io/org/component-c": true,
+			})).To(Equal([]string{"component-b"}))
+		})
+
+		It("should match Docker Hub repositories by either their familiar or normalized name", func() {
+			snapshot.Spec.Components = []ApplicationSnapshotComponent{
+				{Name: "component-a", ContainerImage: "nginx:1.21"},
+				{Name: "component-b", ContainerImage: "docker.io/library/redis:6"},
+			}
+
+			Expect(snapshot.UnapprovedComponents(map[string]bool{
+				"docker.io/library/nginx": true,
+				"redis":                   true,
+			})).To(BeEmpty())
+		})
+
+		It("should treat components with an unparseable image as unapproved", func() {
+			snapshot.Spec.Components[0].ContainerImage = "quay.io/org/Component-A:v1"
+
+			Expect(snapshot.UnapprovedComponents(map[string]bool{
+				"quay.io/org/component-a": true,
+				"quay.io/org/component-b": true,
+			})).To(Equal([]string{"component-a"}))
+		})
+	})
+
+	Context("Testing RecordRetry and NextRetryTime", func() {
+
+		It("should return the zero time when the snapshot was never retried", func() {
+			Expect(snapshot.NextRetryTime(time.Second, time.Minute).IsZero()).To(BeTrue())
+		})
+
+		It("should record the retry count and time", func() {
+			snapshot.RecordRetry()
+			snapshot.RecordRetry()
+
+			Expect(snapshot.Status.RetryCount).To(Equal(int32(2)))
+			Expect(snapshot.Status.LastRetryTime).ToNot(BeNil())
+		})
+
+		It("should grow the retry delay exponentially, up to the maximum", func() {
+			lastRetryTime := metav1.NewTime(time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC))
+			snapshot.Status.LastRetryTime = &lastRetryTime
+
+			for retryCount, expectedDelay := range []time.Duration{
+				1 * time.Second,
+				2 * time.Second,
+				4 * time.Second,
+				8 * time.Second,
+				10 * time.Second,
+				10 * time.Second,
+			} {
+				snapshot.Status.RetryCount = int32(retryCount)
+				Expect(snapshot.NextRetryTime(time.Second, 10*time.Second)).To(Equal(lastRetryTime.Add(expectedDelay)))
+			}
+
+			By("not overflowing for a large retry count")
+			snapshot.Status.RetryCount = 1000
+			Expect(snapshot.NextRetryTime(time.Second, 10*time.Second)).To(Equal(lastRetryTime.Add(10 * time.Second)))
+		})
+
+		It("should retry right after the last retry for a zero or negative base or maximum", func() {
+			lastRetryTime := metav1.NewTime(time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC))
+			snapshot.Status.LastRetryTime = &lastRetryTime
+			snapshot.Status.RetryCount = 3
+
+			Expect(snapshot.NextRetryTime(0, 10*time.Second)).To(Equal(lastRetryTime.Time))
+			Expect(snapshot.NextRetryTime(-time.Second, 10*time.Second)).To(Equal(lastRetryTime.Time))
+			Expect(snapshot.NextRetryTime(time.Second, 0)).To(Equal(lastRetryTime.Time))
+		})
+	})
+
+	Context("Testing ConditionsByTime", func() {
+
+		It("should return the conditions from the most recent to the oldest, sorting ties by type", func() {
+			now := time.Now()
+			snapshot.Status.Conditions = []metav1.Condition{
+				{Type: "Validated", LastTransitionTime: metav1.NewTime(now.Add(-2 * time.Hour))},
+				{Type: "Succeeded", LastTransitionTime: metav1.NewTime(now)},
+				{Type: "Released", LastTransitionTime: metav1.NewTime(now.Add(-1 * time.Hour))},
+				{Type: "Other", LastTransitionTime: metav1.NewTime(now.Add(-1 * time.Hour))},
+			}
+
+			types := []string{}
+			for _, condition := range snapshot.ConditionsByTime() {
+				types = append(types, condition.Type)
+			}
+			Expect(types).To(Equal([]string{"Succeeded", "Other", "Released", "Validated"}))
+
+			By("leaving the original conditions untouched")
+			Expect(snapshot.Status.Conditions[0].Type).To(Equal("Validated"))
+		})
+	})
+
+	Context("Testing StatusOnlyJSON", func() {
+
+		It("should only contain the status of the snapshot", func() {
+			snapshot.MarkRunning()
+
+			statusJSON, err := snapshot.StatusOnlyJSON()
+			Expect(err).To(BeNil())
+
+			parsed := map[string]interface{}{}
+			Expect(json.Unmarshal(statusJSON, &parsed)).To(Succeed())
+			Expect(parsed).To(HaveKey("status"))
+			Expect(parsed).ToNot(HaveKey("spec"))
+			Expect(parsed).ToNot(HaveKey("metadata"))
+
+			status := ApplicationSnapshotStatus{}
+			Expect(json.Unmarshal(statusJSON, &struct {
+				Status *ApplicationSnapshotStatus `json:"status"`
+			}{Status: &status})).To(Succeed())
+			Expect(status.StartTime).ToNot(BeNil())
+			Expect(status.Conditions).To(HaveLen(1))
+		})
+	})
+
+	Context("Testing ContentSupersedes", func() {
+
+		It("should supersede an identical snapshot", func() {
+			Expect(snapshot.ContentSupersedes(snapshot.DeepCopy())).To(BeTrue())
+		})
+
+		It("should supersede a snapshot containing a subset of its components, at the same or different images", func() {
+			other := snapshot.DeepCopy()
+			other.Spec.Components = other.Spec.Components[:1]
+			Expect(snapshot.ContentSupersedes(other)).To(BeTrue())
+
+			other.Spec.Components[0].ContainerImage = "quay.io/org/component-a:v0"
+			Expect(snapshot.ContentSupersedes(other)).To(BeTrue())
+
+			By("not being superseded by the subset")
+			Expect(other.ContentSupersedes(snapshot)).To(BeFalse())
+		})
+
+		It("should not supersede a snapshot with a divergent set of components", func() {
+			other := snapshot.DeepCopy()
+			other.Spec.Components[1] = ApplicationSnapshotComponent{Name: "component-c", ContainerImage: "quay.io/org/component-c:v1"}
+
+			Expect(snapshot.ContentSupersedes(other)).To(BeFalse())
+			Expect(other.ContentSupersedes(snapshot)).To(BeFalse())
+		})
+
+		It("should not supersede a snapshot when its own component image is empty", func() {
+			other := snapshot.DeepCopy()
+			snapshot.Spec.Components[0].ContainerImage = ""
+
+			Expect(snapshot.ContentSupersedes(other)).To(BeFalse())
+		})
+
+		It("should not supersede a nil snapshot", func() {
+			Expect(snapshot.ContentSupersedes(nil)).To(BeFalse())
+		})
+	})
+
+	Context("Testing EventReasonAndMessage", func() {
+
+		It("should describe a pending event when the snapshot has no condition", func() {
+			eventType, reason, _ := snapshot.EventReasonAndMessage()
+			Expect(eventType).To(Equal(corev1.EventTypeNormal))
+			Expect(reason).To(Equal("SnapshotPending"))
+		})
+
+		It("should map each internal reason to the expected event tuple", func() {
+			for _, testCase := range []struct {
+				mark              func(*ApplicationSnapshot)
+				expectedEventType string
+				expectedReason    string
+				expectedMessage   string
+			}{
+				{
+					mark: func(s *ApplicationSnapshot) {
+						s.setStatusConditionWithMessage(metav1.ConditionUnknown, ApplicationSnapshotReasonInitialized, "initialized")
+					},
+					expectedEventType: corev1.EventTypeNormal, expectedReason: "SnapshotInitialized", expectedMessage: "initialized",
+				},
+				{
+					mark:              func(s *ApplicationSnapshot) { s.MarkInvalid(ApplicationSnapshotReasonValidationError, "invalid") },
+					expectedEventType: corev1.EventTypeWarning, expectedReason: "SnapshotValidationFailed", expectedMessage: "invalid",
+				},
+				{
+					mark:              func(s *ApplicationSnapshot) { s.MarkFailed(ApplicationSnapshotReasonTestsFailed, "tests failed") },
+					expectedEventType: corev1.EventTypeWarning, expectedReason: "SnapshotTestsFailed", expectedMessage: "tests failed",
+				},
+				{
+					mark: func(s *ApplicationSnapshot) {
+						s.setStatusConditionWithMessage(metav1.ConditionUnknown, ApplicationSnapshotReasonTestsRunning, "running")
+					},
+					expectedEventType: corev1.EventTypeNormal, expectedReason: "SnapshotTestsRunning", expectedMessage: "running",
+				},
+				{
+					mark: func(s *ApplicationSnapshot) {
+						s.setStatusConditionWithMessage(metav1.ConditionTrue, ApplicationSnapshotReasonSucceeded, "succeeded")
+					},
+					expectedEventType: corev1.EventTypeNormal, expectedReason: "SnapshotSucceeded", expectedMessage: "succeeded",
+				},
+				{
+					mark:              func(s *ApplicationSnapshot) { s.MarkSkipped("skipped") },
+					expectedEventType: corev1.EventTypeNormal, expectedReason: "SnapshotTestsSkipped", expectedMessage: "skipped",
+				},
+				{
+					mark:              func(s *ApplicationSnapshot) { s.MarkFailed("Some Custom-Reason", "custom") },
+					expectedEventType: corev1.EventTypeWarning, expectedReason: "SnapshotSomeCustomReason", expectedMessage: "custom",
+				},
+			} {
+				current := snapshot.DeepCopy()
+				testCase.mark(current)
+
+				eventType, reason, message := current.EventReasonAndMessage()
+				Expect(eventType).To(Equal(testCase.expectedEventType))
+				Expect(reason).To(Equal(testCase.expectedReason))
+				Expect(message).To(Equal(testCase.expectedMessage))
+			}
+		})
+	})
+
+	Context("Testing MergeConditionsFrom", func() {
+
+		var now time.Time
+
+		BeforeEach(func() {
+			now = time.Now()
+			snapshot.Status.Conditions = []metav1.Condition{{
+				Type:               ApplicationSnapshotConditionTypeSucceeded,
+				Status:             metav1.ConditionUnknown,
+				Reason:             ApplicationSnapshotReasonTestsRunning.String(),
+				LastTransitionTime: metav1.NewTime(now),
+			}}
+		})
+
+		It("should merge a newer condition from the other snapshot", func() {
+			other := snapshot.DeepCopy()
+			other.Status.Conditions[0].Status = metav1.ConditionTrue
+			other.Status.Conditions[0].Reason = ApplicationSnapshotReasonSucceeded.String()
+			other.Status.Conditions[0].LastTransitionTime = metav1.NewTime(now.Add(time.Minute))
+			snapshot.SyncPhaseToStatus()
+
+			snapshot.MergeConditionsFrom(other)
+
+			Expect(snapshot.Status.Conditions).To(HaveLen(1))
+			Expect(snapshot.Status.Conditions[0].Status).To(Equal(metav1.ConditionTrue))
+			Expect(snapshot.Status.Conditions[0].Reason).To(Equal(ApplicationSnapshotReasonSucceeded.String()))
+			Expect(snapshot.Status.Phase).To(Equal(ApplicationSnapshotPhaseSucceeded))
+		})
+
+		It("should ignore an older condition from the other snapshot", func() {
+			other := snapshot.DeepCopy()
+			other.Status.Conditions[0].Status = metav1.ConditionFalse
+			other.Status.Conditions[0].Reason = ApplicationSnapshotReasonTestsFailed.String()
+			other.Status.Conditions[0].LastTransitionTime = metav1.NewTime(now.Add(-time.Minute))
+
+			snapshot.MergeConditionsFrom(other)
+
+			Expect(snapshot.Status.Conditions).To(HaveLen(1))
+			Expect(snapshot.Status.Conditions[0].Status).To(Equal(metav1.ConditionUnknown))
+			Expect(snapshot.Status.Conditions[0].Reason).To(Equal(ApplicationSnapshotReasonTestsRunning.String()))
+		})
+
+		It("should add the condition types missing from this snapshot", func() {
+			other := snapshot.DeepCopy()
+			other.Status.Conditions = []metav1.Condition{{
+				Type:               ApplicationSnapshotConditionTypeValidated,
+				Status:             metav1.ConditionTrue,
+				Reason:             "Validated",
+				LastTransitionTime: metav1.NewTime(now.Add(-time.Hour)),
+			}}
+
+			snapshot.MergeConditionsFrom(other)
+
+			Expect(snapshot.Status.Conditions).To(HaveLen(2))
+			Expect(meta.IsStatusConditionTrue(snapshot.Status.Conditions, ApplicationSnapshotConditionTypeValidated)).To(BeTrue())
+		})
+	})
+
+	Context("Testing ValidateArtifactImages", func() {
+
+		It("should accept image sources which reference components of the snapshot", func() {
+			snapshot.Spec.Artifacts.Images = []ImageSource{
+				{Component: "component-a", URL: "https://github.com/org/component-a", Revision: "abc123"},
+				{Component: "component-b"},
+			}
+
+			Expect(snapshot.ValidateArtifactImages()).To(Succeed())
+		})
+
+		It("should reject image sources which reference unknown components", func() {
+			snapshot.Spec.Artifacts.Images = []ImageSource{
+				{Component: "component-a"},
+				{Component: "component-c"},
+				{Component: "component-d"},
+			}
+
+			err := snapshot.ValidateArtifactImages()
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(ContainSubstring(`spec.artifacts.images[1].component: Invalid value: "component-c"`))
+			Expect(err.Error()).To(ContainSubstring(`spec.artifacts.images[2].component: Invalid value: "component-d"`))
+			Expect(err.Error()).ToNot(ContainSubstring("images[0]"))
+		})
+	})
+
+	Context("Testing ApplicationSnapshotList AgeBuckets", func() {
+
+		It("should count the snapshots in each age bucket", func() {
+			now := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+
+			list := &ApplicationSnapshotList{}
+			for _, age := range []time.Duration{
+				time.Minute,
+				59 * time.Minute,
+				time.Hour,
+				23 * time.Hour,
+				24 * time.Hour,
+				25 * time.Hour,
+			} {
+				item := snapshot.DeepCopy()
+				item.CreationTimestamp = metav1.NewTime(now.Add(-age))
+				list.Items = append(list.Items, *item)
+			}
+
+			Expect(list.AgeBuckets(now)).To(Equal(map[string]int{
+				AgeBucketLessThanHour: 2,
+				AgeBucketHourToDay:    3,
+				AgeBucketMoreThanDay:  1,
+			}))
+		})
+
+		It("should return empty buckets for an empty list", func() {
+			list := &ApplicationSnapshotList{}
+			Expect(list.AgeBuckets(time.Now())).To(Equal(map[string]int{
+				AgeBucketLessThanHour: 0,
+				AgeBucketHourToDay:    0,
+				AgeBucketMoreThanDay:  0,
+			}))
+		})
+	})
+
+	Context("Testing MissingApplicationComponents", func() {
+
+		BeforeEach(func() {
+			snapshot.Spec.Type = ApplicationSnapshotTypeComposite
+		})
+
+		It("should return no components for a complete composite snapshot", func() {
+			Expect(snapshot.MissingApplicationComponents([]string{"component-a", "component-b"})).To(BeEmpty())
+		})
+
+		It("should return the application components missing from an incomplete composite snapshot", func() {
+			Expect(snapshot.MissingApplicationComponents([]string{"component-a", "component-b", "component-c", "component-d"})).
+				To(Equal([]string{"component-c", "component-d"}))
+		})
+	})
+
+	Context("Testing ToPromotionManifest", func() {
+
+		It("should produce a manifest which can be parsed back", func() {
+			manifestYAML, err := snapshot.ToPromotionManifest("staging")
+			Expect(err).To(BeNil())
+
+			manifest := PromotionManifest{}
+			Expect(yaml.Unmarshal(manifestYAML, &manifest)).To(Succeed())
+			Expect(manifest).To(Equal(PromotionManifest{
+				Application: "my-app",
+				Environment: "staging",
+				Snapshot:    "my-snapshot",
+				Components:  snapshot.Spec.Components,
+			}))
+		})
+
+		It("should reject an environment name which is not a valid DNS-1123 label", func() {
+			for _, environment := range []string{"", "Staging", "staging_env", "-staging"} {
+				manifestYAML, err := snapshot.ToPromotionManifest(environment)
+				Expect(err).ToNot(BeNil())
+				Expect(manifestYAML).To(BeNil())
+			}
+		})
+	})
+
+	Context("Testing ValidateStatusConsistency", func() {
+
+		It("should accept consistent statuses", func() {
+			Expect(snapshot.ValidateStatusConsistency()).To(Succeed())
+
+			snapshot.MarkRunning()
+			snapshot.Status.IntegrationTestPipelineRun = "my-namespace/my-test-pipelinerun"
+			Expect(snapshot.ValidateStatusConsistency()).To(Succeed())
+
+			snapshot.MarkSucceeded()
+			Expect(snapshot.ValidateStatusConsistency()).To(Succeed())
+
+			invalid := &ApplicationSnapshot{}
+			invalid.MarkInvalid(ApplicationSnapshotReasonValidationError, "invalid")
+			Expect(invalid.ValidateStatusConsistency()).To(Succeed())
+		})
+
+		It("should reject a completion time before the start time", func() {
+			snapshot.MarkRunning()
+			snapshot.MarkSucceeded()
+			snapshot.Status.CompletionTime = &metav1.Time{Time: snapshot.Status.StartTime.Add(-time.Minute)}
+
+			err := snapshot.ValidateStatusConsistency()
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(ContainSubstring("must not be before the start time"))
+		})
+
+		It("should reject a running snapshot with a completion time", func() {
+			snapshot.MarkRunning()
+			snapshot.Status.CompletionTime = &metav1.Time{Time: snapshot.Status.StartTime.Add(time.Minute)}
+
+			err := snapshot.ValidateStatusConsistency()
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(ContainSubstring("must not be set while the snapshot is running"))
+		})
+
+		It("should reject a running snapshot without an integration test PipelineRun", func() {
+			snapshot.MarkRunning()
+
+			err := snapshot.ValidateStatusConsistency()
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(ContainSubstring("status.integrationTestPipelineRun"))
+			Expect(err.Error()).To(ContainSubstring("must be set while the integration tests are running"))
+
+			By("accepting the running snapshot once it references its integration test PipelineRun")
+			snapshot.Status.IntegrationTestPipelineRun = "my-namespace/my-test-pipelinerun"
+			Expect(snapshot.ValidateStatusConsistency()).To(Succeed())
+		})
+
+		It("should reject a started snapshot which is done without a completion time", func() {
+			snapshot.MarkRunning()
+			snapshot.MarkFailed(ApplicationSnapshotReasonTestsFailed, "tests failed")
+			snapshot.Status.CompletionTime = nil
+
+			err := snapshot.ValidateStatusConsistency()
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(ContainSubstring("must be set once the snapshot is done"))
+		})
+
+		It("should reject a snapshot which is done without having started and without a completion time", func() {
+			snapshot.MarkInvalid(ApplicationSnapshotReasonValidationError, "invalid")
+			snapshot.Status.CompletionTime = nil
+
+			err := snapshot.ValidateStatusConsistency()
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(ContainSubstring("must be set once the snapshot is done"))
+		})
+	})
+
+	Context("Testing ComponentFingerprint", func() {
+
+		It("should be deterministic and independent of the component order and display fields", func() {
+			fingerprint := snapshot.ComponentFingerprint()
+			Expect(snapshot.ComponentFingerprint()).To(Equal(fingerprint))
+
+			other := snapshot.DeepCopy()
+			other.Name = "other-snapshot"
+			other.Spec.DisplayName = "Other snapshot"
+			other.Spec.DisplayDescription = "Another snapshot with the same components"
+			other.Spec.Components[0], other.Spec.Components[1] = other.Spec.Components[1], other.Spec.Components[0]
+			Expect(other.ComponentFingerprint()).To(Equal(fingerprint))
+		})
+
+		It("should change when the components change", func() {
+			fingerprint := snapshot.ComponentFingerprint()
+
+			snapshot.Spec.Components[0].ContainerImage = "quay.io/org/component-a:v2"
+			Expect(snapshot.ComponentFingerprint()).ToNot(Equal(fingerprint))
+		})
+
+		It("should be a valid label value", func() {
+			fingerprint := snapshot.ComponentFingerprint()
+			Expect(validation.IsValidLabelValue(fingerprint)).To(BeEmpty())
+			Expect(len(fingerprint)).To(BeNumerically("<=", validation.LabelValueMaxLength))
+
+			empty := &ApplicationSnapshot{}
+			Expect(validation.IsValidLabelValue(empty.ComponentFingerprint())).To(BeEmpty())
+		})
+	})
+
+	Context("Testing Phase and PhaseCondition", func() {
+
+		It("should be pending without a condition", func() {
+			Expect(snapshot.Phase()).To(Equal(ApplicationSnapshotPhasePending))
+			Expect(snapshot.PhaseCondition()).To(BeNil())
+		})
+
+		It("should return the Succeeded condition for each phase", func() {
+			phases := map[string]func(s *ApplicationSnapshot){
+				ApplicationSnapshotPhaseRunning: func(s *ApplicationSnapshot) { s.MarkRunning() },
+				ApplicationSnapshotPhaseSucceeded: func(s *ApplicationSnapshot) {
+					s.MarkRunning()
+					s.MarkSucceeded()
+				},
+				ApplicationSnapshotPhaseSkipped: func(s *ApplicationSnapshot) { s.MarkSkipped("skipped by policy") },
+				ApplicationSnapshotPhaseFailed: func(s *ApplicationSnapshot) {
+					s.MarkRunning()
+					s.MarkFailed(ApplicationSnapshotReasonTestsFailed, "tests failed")
+				},
+				ApplicationSnapshotPhaseInvalid: func(s *ApplicationSnapshot) {
+					s.MarkInvalid(ApplicationSnapshotReasonValidationError, "invalid")
+				},
+			}
+
+			for phase, mark := range phases {
+				s := snapshot.DeepCopy()
+				mark(s)
+
+				Expect(s.Phase()).To(Equal(phase))
+				condition := s.PhaseCondition()
+				Expect(condition).ToNot(BeNil())
+				Expect(condition.Type).To(Equal(ApplicationSnapshotConditionTypeSucceeded))
+			}
+		})
+	})
+
+	Context("Testing IsNoOpRelease", func() {
+
+		It("should not be a no-op without a last released snapshot", func() {
+			Expect(snapshot.IsNoOpRelease(nil)).To(BeFalse())
+		})
+
+		It("should be a no-op when the components are identical, regardless of their order", func() {
+			lastReleased := snapshot.DeepCopy()
+			lastReleased.Spec.Components[0], lastReleased.Spec.Components[1] = lastReleased.Spec.Components[1], lastReleased.Spec.Components[0]
+
+			Expect(snapshot.IsNoOpRelease(lastReleased)).To(BeTrue())
+		})
+
+		It("should not be a no-op when an image changed", func() {
+			lastReleased := snapshot.DeepCopy()
+			snapshot.Spec.Components[0].ContainerImage = "quay.io/org/component-a:v2"
+
+			Expect(snapshot.IsNoOpRelease(lastReleased)).To(BeFalse())
+		})
+
+		It("should not be a no-op when a component was added", func() {
+			lastReleased := snapshot.DeepCopy()
+			snapshot.Spec.Components = append(snapshot.Spec.Components,
+				ApplicationSnapshotComponent{Name: "component-c", ContainerImage: "quay.io/org/component-c:v1"})
+
+			Expect(snapshot.IsNoOpRelease(lastReleased)).To(BeFalse())
+		})
+	})
+
+	Context("Testing ForEachComponent", func() {
+
+		It("should allow modifying the components in place", func() {
+			err := snapshot.ForEachComponent(func(i int, c *ApplicationSnapshotComponent) error {
+				c.ContainerImage = strings.Replace(c.ContainerImage, ":v1", ":v2", 1)
+				return nil
+			})
+			Expect(err).To(BeNil())
+
+			Expect(snapshot.Spec.Components[0].ContainerImage).To(Equal("quay.io/org/component-a:v2"))
+			Expect(snapshot.Spec.Components[1].ContainerImage).To(Equal("quay.io/org/component-b:v2"))
+		})
+
+		It("should stop at and return the first error", func() {
+			visited := []int{}
+			err := snapshot.ForEachComponent(func(i int, c *ApplicationSnapshotComponent) error {
+				visited = append(visited, i)
+				return fmt.Errorf("failed to process %s", c.Name)
+			})
+
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(Equal("failed to process component-a"))
+			Expect(visited).To(Equal([]int{0}))
+		})
+	})
+
+	Context("Testing TestOutcome", func() {
+
+		It("should not be known when the tests haven't started", func() {
+			passed, reason, ok := snapshot.TestOutcome()
+			Expect(ok).To(BeFalse())
+			Expect(passed).To(BeFalse())
+			Expect(reason).To(BeEmpty())
+		})
+
+		It("should not be known when the tests are still running", func() {
+			snapshot.MarkRunning()
+
+			_, _, ok := snapshot.TestOutcome()
+			Expect(ok).To(BeFalse())
+		})
+
+		It("should have passed when the snapshot succeeded", func() {
+			snapshot.MarkRunning()
+			snapshot.MarkSucceeded()
+
+			passed, reason, ok := snapshot.TestOutcome()
+			Expect(ok).To(BeTrue())
+			Expect(passed).To(BeTrue())
+			Expect(reason).To(Equal(ApplicationSnapshotReasonSucceeded))
+		})
+
+		It("should have passed when the tests were skipped", func() {
+			snapshot.MarkSkipped("skipped by policy")
+
+			passed, reason, ok := snapshot.TestOutcome()
+			Expect(ok).To(BeTrue())
+			Expect(passed).To(BeTrue())
+			Expect(reason).To(Equal(ApplicationSnapshotReasonSkipped))
+		})
+
+		It("should not have passed when the tests failed", func() {
+			snapshot.MarkRunning()
+			snapshot.MarkFailed(ApplicationSnapshotReasonTestsFailed, "tests failed")
+
+			passed, reason, ok := snapshot.TestOutcome()
+			Expect(ok).To(BeTrue())
+			Expect(passed).To(BeFalse())
+			Expect(reason).To(Equal(ApplicationSnapshotReasonTestsFailed))
+		})
+
+		It("should not have passed when the snapshot is invalid", func() {
+			snapshot.MarkInvalid(ApplicationSnapshotReasonValidationError, "invalid")
+
+			passed, reason, ok := snapshot.TestOutcome()
+			Expect(ok).To(BeTrue())
+			Expect(passed).To(BeFalse())
+			Expect(reason).To(Equal(ApplicationSnapshotReasonValidationError))
+		})
+	})
+
+	Context("Testing Sanitize", func() {
+
+		It("should keep the latest condition of each type", func() {
+			now := time.Now()
+			snapshot.Status.Conditions = []metav1.Condition{
+				{Type: ApplicationSnapshotConditionTypeSucceeded, Status: metav1.ConditionFalse, LastTransitionTime: metav1.NewTime(now)},
+				{Type: ApplicationSnapshotConditionTypeValidated, Status: metav1.ConditionTrue, LastTransitionTime: metav1.NewTime(now)},
+				{Type: ApplicationSnapshotConditionTypeSucceeded, Status: metav1.ConditionTrue, LastTransitionTime: metav1.NewTime(now.Add(-time.Hour))},
+				{Type: ApplicationSnapshotConditionTypeValidated, Status: metav1.ConditionFalse, LastTransitionTime: metav1.NewTime(now.Add(time.Hour))},
+			}
+
+			snapshot.Sanitize()
+
+			Expect(snapshot.Status.Conditions).To(HaveLen(2))
+			Expect(snapshot.Status.Conditions[0].Type).To(Equal(ApplicationSnapshotConditionTypeSucceeded))
+			Expect(snapshot.Status.Conditions[0].Status).To(Equal(metav1.ConditionFalse))
+			Expect(snapshot.Status.Conditions[1].Type).To(Equal(ApplicationSnapshotConditionTypeValidated))
+			Expect(snapshot.Status.Conditions[1].Status).To(Equal(metav1.ConditionFalse))
+		})
+
+		It("should cap the number of conditions, keeping the most recent ones", func() {
+			now := time.Now()
+			for i := 0; i < MaxApplicationSnapshotConditions*2; i++ {
+				condition := metav1.Condition{
+					Type:               fmt.Sprintf("Condition%d", i),
+					Status:             metav1.ConditionTrue,
+					LastTransitionTime: metav1.NewTime(now.Add(time.Duration(i) * time.Minute)),
+				}
+				// Add every condition twice, to also exercise the deduplication
+				snapshot.Status.Conditions = append(snapshot.Status.Conditions, condition, condition)
+			}
+
+			snapshot.Sanitize()
+
+			Expect(snapshot.Status.Conditions).To(HaveLen(MaxApplicationSnapshotConditions))
+			Expect(snapshot.Status.Conditions[0].Type).To(Equal(fmt.Sprintf("Condition%d", MaxApplicationSnapshotConditions)))
+			Expect(snapshot.Status.Conditions[MaxApplicationSnapshotConditions-1].Type).To(Equal(fmt.Sprintf("Condition%d", MaxApplicationSnapshotConditions*2-1)))
+		})
+	})
+
+	Context("Testing ApplicationSnapshotList.Events", func() {
+
+		var previous *ApplicationSnapshotList
+
+		BeforeEach(func() {
+			snapshot.ResourceVersion = "1"
+			other := snapshot.DeepCopy()
+			other.Name = "other-snapshot"
+
+			previous = &ApplicationSnapshotList{Items: []ApplicationSnapshot{*snapshot, *other}}
+		})
+
+		It("should emit no events when nothing changed", func() {
+			current := previous.DeepCopy()
+			Expect(current.Events(previous)).To(BeEmpty())
+		})
+
+		It("should emit an Added event for every snapshot without a previous list", func() {
+			events := previous.Events(nil)
+			Expect(events).To(HaveLen(2))
+			Expect(events[0].Type).To(Equal(SnapshotEventAdded))
+			Expect(events[1].Type).To(Equal(SnapshotEventAdded))
+		})
+
+		It("should emit an Added event for a new snapshot", func() {
+			current := previous.DeepCopy()
+			added := snapshot.DeepCopy()
+			added.Name = "added-snapshot"
+			current.Items = append(current.Items, *added)
+
+			events := current.Events(previous)
+			Expect(events).To(HaveLen(1))
+			Expect(events[0].Type).To(Equal(SnapshotEventAdded))
+			Expect(events[0].Object.Name).To(Equal("added-snapshot"))
+		})
+
+		It("should emit a Modified event for a snapshot with a different resourceVersion", func() {
+			current := previous.DeepCopy()
+			current.Items[1].ResourceVersion = "2"
+
+			events := current.Events(previous)
+			Expect(events).To(HaveLen(1))
+			Expect(events[0].Type).To(Equal(SnapshotEventModified))
+			Expect(events[0].Object.Name).To(Equal("other-snapshot"))
+		})
+
+		It("should compare the snapshots themselves when they have no resourceVersion", func() {
+			for i := range previous.Items {
+				previous.Items[i].ResourceVersion = ""
+			}
+			current := previous.DeepCopy()
+			Expect(current.Events(previous)).To(BeEmpty())
+
+			current.Items[0].Spec.DisplayName = "Updated snapshot"
+			events := current.Events(previous)
+			Expect(events).To(HaveLen(1))
+			Expect(events[0].Type).To(Equal(SnapshotEventModified))
+			Expect(events[0].Object.Name).To(Equal("my-snapshot"))
+		})
+
+		It("should emit a Deleted event for a removed snapshot", func() {
+			current := previous.DeepCopy()
+			current.Items = current.Items[:1]
+
+			events := current.Events(previous)
+			Expect(events).To(HaveLen(1))
+			Expect(events[0].Type).To(Equal(SnapshotEventDeleted))
+			Expect(events[0].Object.Name).To(Equal("other-snapshot"))
+		})
+
+		It("should match snapshots by namespace as well as name", func() {
+			current := previous.DeepCopy()
+			current.Items[1].Namespace = "other-namespace"
+
+			events := current.Events(previous)
+			Expect(events).To(HaveLen(2))
+			Expect(events[0].Type).To(Equal(SnapshotEventAdded))
+			Expect(events[0].Object.Namespace).To(Equal("other-namespace"))
+			Expect(events[1].Type).To(Equal(SnapshotEventDeleted))
+			Expect(events[1].Object.Namespace).To(Equal("my-namespace"))
+		})
+	})
+
+	Context("Testing MarkReleasedToEnvironment and IsReleasedTo", func() {
+
+		It("should not be released to any environment initially", func() {
+			Expect(snapshot.IsReleasedTo("staging")).To(BeFalse())
+		})
+
+		It("should record the environments the snapshot was released to", func() {
+			snapshot.MarkReleasedToEnvironment("staging")
+			snapshot.MarkReleasedToEnvironment("production")
+
+			Expect(snapshot.IsReleasedTo("staging")).To(BeTrue())
+			Expect(snapshot.IsReleasedTo("production")).To(BeTrue())
+			Expect(snapshot.IsReleasedTo("development")).To(BeFalse())
+			Expect(snapshot.Status.ReleasedEnvironments).To(Equal([]string{"staging", "production"}))
+		})
+
+		It("should record each environment only once", func() {
+			snapshot.MarkReleasedToEnvironment("staging")
+			snapshot.MarkReleasedToEnvironment("staging")
+
+			Expect(snapshot.Status.ReleasedEnvironments).To(Equal([]string{"staging"}))
+		})
+	})
+
+	Context("Testing RequiredRBACRules", func() {
+
+		It("should allow reading and watching snapshots, and updating their status", func() {
+			verbs := map[string][]string{}
+			for _, rule := range RequiredRBACRules() {
+				Expect(rule.APIGroups).To(Equal([]string{"appstudio.redhat.com"}))
+				for _, resource := range rule.Resources {
+					verbs[resource] = append(verbs[resource], rule.Verbs...)
+				}
+			}
+
+			Expect(verbs["applicationsnapshots"]).To(ContainElements("get", "list", "watch"))
+			Expect(verbs["applicationsnapshots/status"]).To(ContainElements("get", "update"))
+		})
+
+		It("should not grant creating or deleting snapshots", func() {
+			for _, rule := range RequiredRBACRules() {
+				Expect(rule.Verbs).ToNot(ContainElement("create"))
+				Expect(rule.Verbs).ToNot(ContainElement("delete"))
+			}
+		})
+	})
+
+	Context("Testing EffectiveCompletionTime", func() {
+
+		It("should return the completion time of a completed snapshot", func() {
+			snapshot.MarkRunning()
+			snapshot.MarkSucceeded()
+
+			Expect(snapshot.EffectiveCompletionTime()).To(Equal(*snapshot.Status.CompletionTime))
+		})
+
+		It("should return the sentinel for a running snapshot", func() {
+			snapshot.MarkRunning()
+
+			Expect(snapshot.EffectiveCompletionTime()).To(Equal(CompletionTimeSentinel))
+		})
+
+		It("should sort running snapshots after the completed ones", func() {
+			now := time.Now()
+			running := snapshot.DeepCopy()
+			running.Name = "running"
+			running.MarkRunning()
+			recent := snapshot.DeepCopy()
+			recent.Name = "recent"
+			recent.Status.CompletionTime = &metav1.Time{Time: now}
+			old := snapshot.DeepCopy()
+			old.Name = "old"
+			old.Status.CompletionTime = &metav1.Time{Time: now.Add(-time.Hour)}
+
+			snapshots := []ApplicationSnapshot{*running, *recent, *old}
+			sort.Slice(snapshots, func(i, j int) bool {
+				iTime, jTime := snapshots[i].EffectiveCompletionTime(), snapshots[j].EffectiveCompletionTime()
+				return iTime.Before(&jTime)
+			})
+
+			Expect([]string{snapshots[0].Name, snapshots[1].Name, snapshots[2].Name}).To(Equal([]string{"old", "recent", "running"}))
+		})
+	})
+
+	Context("Testing SetRollbackTarget and GetRollbackTarget", func() {
+
+		It("should not have a rollback target initially", func() {
+			_, exists := snapshot.GetRollbackTarget()
+			Expect(exists).To(BeFalse())
+		})
+
+		It("should record and return the rollback target", func() {
+			Expect(snapshot.SetRollbackTarget("my-namespace/previous-snapshot")).To(Succeed())
+
+			nn, exists := snapshot.GetRollbackTarget()
+			Expect(exists).To(BeTrue())
+			Expect(nn).To(Equal("my-namespace/previous-snapshot"))
+			Expect(snapshot.Annotations).To(HaveKeyWithValue(ApplicationSnapshotRollbackTargetAnnotation, "my-namespace/previous-snapshot"))
+		})
+
+		It("should reject a rollback target which is not a namespaced name", func() {
+			err := snapshot.SetRollbackTarget("previous-snapshot")
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(ContainSubstring("is not a valid namespaced name"))
+			Expect(snapshot.Annotations).ToNot(HaveKey(ApplicationSnapshotRollbackTargetAnnotation))
+		})
+
+		It("should ignore an invalid rollback target annotation", func() {
+			snapshot.Annotations = map[string]string{ApplicationSnapshotRollbackTargetAnnotation: "Not/A/Namespaced/Name"}
+
+			_, exists := snapshot.GetRollbackTarget()
+			Expect(exists).To(BeFalse())
+		})
+	})
+
+	Context("Testing ValidateSingleRegistry", func() {
+
+		It("should accept components from a single registry", func() {
+			Expect(snapshot.ValidateSingleRegistry()).To(Succeed())
+		})
+
+		It("should list the components whose registry differs from the first component's", func() {
+			snapshot.Spec.Components = append(snapshot.Spec.Components,
+				ApplicationSnapshotComponent{Name: "component-c", ContainerImage: "docker.io/org/component-c:v1"},
+				ApplicationSnapshotComponent{Name: "component-d", ContainerImage: "org/component-d:v1"})
+
+			err := snapshot.ValidateSingleRegistry()
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(ContainSubstring("spec.components[2].containerImage"))
+			Expect(err.Error()).To(ContainSubstring("registry docker.io differs from the registry quay.io of component component-a"))
+			Expect(err.Error()).To(ContainSubstring("spec.components[3].containerImage"))
+			Expect(err.Error()).ToNot(ContainSubstring("spec.components[1]"))
+		})
+	})
+
+	Context("Testing ChangedSince", func() {
+
+		BeforeEach(func() {
+			snapshot.ResourceVersion = "42"
+		})
+
+		It("should always have changed since an empty resourceVersion", func() {
+			Expect(snapshot.ChangedSince("")).To(BeTrue())
+
+			snapshot.ResourceVersion = ""
+			Expect(snapshot.ChangedSince("")).To(BeTrue())
+		})
+
+		It("should not have changed since its current resourceVersion", func() {
+			Expect(snapshot.ChangedSince("42")).To(BeFalse())
+		})
+
+		It("should have changed since a different resourceVersion", func() {
+			Expect(snapshot.ChangedSince("41")).To(BeTrue())
+		})
+	})
+
+	Context("Testing ComponentLabel", func() {
+
+		BeforeEach(func() {
+			snapshot.Spec.Components[0].Labels = map[string]string{"team": "frontend"}
+		})
+
+		It("should return the label of a component", func() {
+			value, exists := snapshot.ComponentLabel("component-a", "team")
+			Expect(exists).To(BeTrue())
+			Expect(value).To(Equal("frontend"))
+		})
+
+		It("should report a missing label or component", func() {
+			_, exists := snapshot.ComponentLabel("component-a", "owner")
+			Expect(exists).To(BeFalse())
+
+			_, exists = snapshot.ComponentLabel("component-b", "team")
+			Expect(exists).To(BeFalse())
+
+			_, exists = snapshot.ComponentLabel("component-c", "team")
+			Expect(exists).To(BeFalse())
+		})
+
+		It("should deep copy the component labels", func() {
+			copied := snapshot.DeepCopy()
+			copied.Spec.Components[0].Labels["team"] = "backend"
+
+			value, _ := snapshot.ComponentLabel("component-a", "team")
+			Expect(value).To(Equal("frontend"))
+		})
+	})
+
+	Context("Testing Duration and MetSLO", func() {
+
+		BeforeEach(func() {
+			snapshot.MarkRunning()
+		})
+
+		It("should have met the SLO when done within it", func() {
+			snapshot.MarkSucceeded()
+			snapshot.Status.CompletionTime = &metav1.Time{Time: snapshot.Status.StartTime.Add(10 * time.Minute)}
+
+			Expect(snapshot.Duration()).To(Equal(10 * time.Minute))
+			met, measurable := snapshot.MetSLO(10 * time.Minute)
+			Expect(measurable).To(BeTrue())
+			Expect(met).To(BeTrue())
+		})
+
+		It("should not have met the SLO when done over it", func() {
+			snapshot.MarkFailed(ApplicationSnapshotReasonTestsFailed, "tests failed")
+			snapshot.Status.CompletionTime = &metav1.Time{Time: snapshot.Status.StartTime.Add(time.Hour)}
+
+			met, measurable := snapshot.MetSLO(10 * time.Minute)
+			Expect(measurable).To(BeTrue())
+			Expect(met).To(BeFalse())
+		})
+
+		It("should not be measurable when not done", func() {
+			Expect(snapshot.Duration()).To(BeZero())
+
+			met, measurable := snapshot.MetSLO(10 * time.Minute)
+			Expect(measurable).To(BeFalse())
+			Expect(met).To(BeFalse())
+		})
+	})
+
+	Context("Testing IsStaleRelativeTo", func() {
+
+		var latest *ApplicationSnapshot
+
+		BeforeEach(func() {
+			snapshot.ResourceVersion = "1"
+			snapshot.Generation = 1
+			snapshot.MarkRunning()
+			snapshot.Status.Conditions[0].ObservedGeneration = 1
+			latest = snapshot.DeepCopy()
+		})
+
+		It("should not be stale relative to an identical copy or a nil one", func() {
+			Expect(snapshot.IsStaleRelativeTo(latest)).To(BeFalse())
+			Expect(snapshot.IsStaleRelativeTo(nil)).To(BeFalse())
+		})
+
+		It("should be stale when the resourceVersion differs", func() {
+			latest.ResourceVersion = "2"
+			Expect(snapshot.IsStaleRelativeTo(latest)).To(BeTrue())
+		})
+
+		It("should be stale when the generation is older", func() {
+			snapshot.ResourceVersion, latest.ResourceVersion = "", ""
+			latest.Generation = 2
+			Expect(snapshot.IsStaleRelativeTo(latest)).To(BeTrue())
+		})
+
+		It("should be stale when the condition was observed for an older generation", func() {
+			latest.Status.Conditions[0].ObservedGeneration = 2
+			Expect(snapshot.IsStaleRelativeTo(latest)).To(BeTrue())
+
+			By("not considering the latest copy stale relative to this one")
+			Expect(latest.IsStaleRelativeTo(snapshot)).To(BeFalse())
+		})
+	})
+
+	Context("Testing ComponentsCSV", func() {
+
+		It("should start with a header line and sort the components by name", func() {
+			snapshot.Spec.Components[0], snapshot.Spec.Components[1] = snapshot.Spec.Components[1], snapshot.Spec.Components[0]
+
+			Expect(snapshot.ComponentsCSV()).To(Equal("name,containerImage\n" +
+				"component-a,quay.io/org/component-a:v1\n" +
+				"component-b,quay.io/org/component-b:v1\n"))
+
+			By("not reordering the components of the snapshot")
+			Expect(snapshot.Spec.Components[0].Name).To(Equal("component-b"))
+		})
+
+		It("should only contain the header line without components", func() {
+			snapshot.Spec.Components = nil
+			Expect(snapshot.ComponentsCSV()).To(Equal("name,containerImage\n"))
+		})
+
+		It("should quote fields containing commas or quotes", func() {
+			snapshot.Spec.Components = []ApplicationSnapshotComponent{
+				{Name: "component-a", ContainerImage: `quay.io/org/component-a:v1,"latest"`},
+			}
+
+			Expect(snapshot.ComponentsCSV()).To(Equal("name,containerImage\n" +
+				`component-a,"quay.io/org/component-a:v1,""latest"""` + "\n"))
+		})
+	})
+
+	Context("Testing TotalWeight and NormalizeWeights", func() {
+
+		weight := func(w int32) *int32 {
+			return &w
+		}
+
+		BeforeEach(func() {
+			snapshot.Spec.Components = append(snapshot.Spec.Components,
+				ApplicationSnapshotComponent{Name: "component-c", ContainerImage: "quay.io/org/component-c:v1"})
+		})
+
+		It("should sum the weights of the components which have one", func() {
+			Expect(snapshot.TotalWeight()).To(BeZero())
+
+			snapshot.Spec.Components[0].Weight = weight(20)
+			snapshot.Spec.Components[2].Weight = weight(30)
+			Expect(snapshot.TotalWeight()).To(Equal(int32(50)))
+		})
+
+		It("should distribute the remaining weight between the components without one", func() {
+			snapshot.Spec.Components[0].Weight = weight(20)
+			Expect(snapshot.NormalizeWeights()).To(Succeed())
+
+			Expect(*snapshot.Spec.Components[0].Weight).To(Equal(int32(20)))
+			Expect(*snapshot.Spec.Components[1].Weight).To(Equal(int32(40)))
+			Expect(*snapshot.Spec.Components[2].Weight).To(Equal(int32(40)))
+			Expect(snapshot.TotalWeight()).To(Equal(int32(MaxComponentWeight)))
+		})
+
+		It("should give the rest of the division to the first components", func() {
+			Expect(snapshot.NormalizeWeights()).To(Succeed())
+
+			Expect(*snapshot.Spec.Components[0].Weight).To(Equal(int32(34)))
+			Expect(*snapshot.Spec.Components[1].Weight).To(Equal(int32(33)))
+			Expect(*snapshot.Spec.Components[2].Weight).To(Equal(int32(33)))
+		})
+
+		It("should fail when the weights already exceed the maximum", func() {
+			snapshot.Spec.Components[0].Weight = weight(60)
+			snapshot.Spec.Components[1].Weight = weight(60)
+
+			err := snapshot.NormalizeWeights()
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(ContainSubstring("exceeds 100"))
+			Expect(snapshot.Spec.Components[2].Weight).To(BeNil())
+		})
+	})
+
+	Context("Testing SyncPhaseToStatus", func() {
+
+		It("should not set a phase before any condition is set", func() {
+			Expect(snapshot.Status.Phase).To(BeEmpty())
+
+			snapshot.SyncPhaseToStatus()
+			Expect(snapshot.Status.Phase).To(Equal(ApplicationSnapshotPhasePending))
+		})
+
+		It("should keep the phase in sync on every Mark call", func() {
+			snapshot.MarkRunning()
+			Expect(snapshot.Status.Phase).To(Equal(ApplicationSnapshotPhaseRunning))
+
+			snapshot.MarkFailed(ApplicationSnapshotReasonTestsFailed, "tests failed")
+			Expect(snapshot.Status.Phase).To(Equal(ApplicationSnapshotPhaseFailed))
+
+			skipped := &ApplicationSnapshot{}
+			skipped.MarkSkipped("skipped by policy")
+			Expect(skipped.Status.Phase).To(Equal(ApplicationSnapshotPhaseSkipped))
+
+			succeeded := &ApplicationSnapshot{}
+			succeeded.MarkRunning()
+			succeeded.MarkSucceeded()
+			Expect(succeeded.Status.Phase).To(Equal(ApplicationSnapshotPhaseSucceeded))
+
+			invalid := &ApplicationSnapshot{}
+			invalid.MarkInvalid(ApplicationSnapshotReasonValidationError, "invalid")
+			Expect(invalid.Status.Phase).To(Equal(ApplicationSnapshotPhaseInvalid))
+		})
+
+		It("should serialize the phase as a top-level status field", func() {
+			snapshot.MarkRunning()
+
+			statusJSON, err := snapshot.StatusOnlyJSON()
+			Expect(err).To(BeNil())
+			Expect(string(statusJSON)).To(ContainSubstring(`"phase":"Running"`))
+		})
+	})
+
+	Context("Testing RecordApplicationGeneration and ApplicationDrifted", func() {
+
+		It("should be drifted when no generation was recorded", func() {
+			Expect(snapshot.ApplicationDrifted(1)).To(BeTrue())
+		})
+
+		It("should not be drifted when the generation matches", func() {
+			snapshot.RecordApplicationGeneration(3)
+
+			Expect(snapshot.Status.ObservedApplicationGeneration).To(Equal(int64(3)))
+			Expect(snapshot.ApplicationDrifted(3)).To(BeFalse())
+		})
+
+		It("should be drifted when the Application generation changed", func() {
+			snapshot.RecordApplicationGeneration(3)
+
+			Expect(snapshot.ApplicationDrifted(4)).To(BeTrue())
+		})
+	})
+
+	Context("Testing NewApplicationSnapshotFromImages", func() {
+
+		images := map[string]string{
+			"component-b": "quay.io/org/component-b:v1",
+			"component-a": "quay.io/org/component-a:v1",
+		}
+
+		It("should create a snapshot with sorted components", func() {
+			created := NewApplicationSnapshotFromImages("my-snapshot", "my-app", images)
+			Expect(created).ToNot(BeNil())
+
+			Expect(created.Spec.Application).To(Equal("my-app"))
+			Expect(created.Spec.Components).To(Equal(snapshot.Spec.Components))
+			Expect(created.Name).To(HavePrefix("my-snapshot-"))
+			Expect(validation.IsDNS1123Subdomain(created.Name)).To(BeEmpty())
+		})
+
+		It("should be deterministic", func() {
+			created := NewApplicationSnapshotFromImages("my-snapshot", "my-app", images)
+			Expect(NewApplicationSnapshotFromImages("my-snapshot", "my-app", images)).To(Equal(created))
+			Expect(created.Name).To(Equal("my-snapshot-" + snapshot.ComponentFingerprint()))
+
+			By("changing the name when the images change")
+			otherImages := map[string]string{"component-a": "quay.io/org/component-a:v2"}
+			Expect(NewApplicationSnapshotFromImages("my-snapshot", "my-app", otherImages).Name).ToNot(Equal(created.Name))
+		})
+
+		It("should return nil for an empty map", func() {
+			Expect(NewApplicationSnapshotFromImages("my-snapshot", "my-app", map[string]string{})).To(BeNil())
+			Expect(NewApplicationSnapshotFromImages("my-snapshot", "my-app", nil)).To(BeNil())
+		})
+
+		It("should return nil for an invalid application name", func() {
+			Expect(NewApplicationSnapshotFromImages("my-snapshot", "My App", images)).To(BeNil())
+			Expect(NewApplicationSnapshotFromImages("my-snapshot", "", images)).To(BeNil())
+		})
+
+		It("should return nil for an invalid image or snapshot name", func() {
+			Expect(NewApplicationSnapshotFromImages("my-snapshot", "my-app", map[string]string{"component-a": "Not An Image"})).To(BeNil())
+			Expect(NewApplicationSnapshotFromImages("my-snapshot", "my-app", map[string]string{"component-a": ""})).To(BeNil())
+			Expect(NewApplicationSnapshotFromImages("My Snapshot", "my-app", images)).To(BeNil())
+		})
+	})
+
+	Context("Testing StaleConditions", func() {
+
+		It("should return no conditions without any", func() {
+			snapshot.Generation = 2
+			Expect(snapshot.StaleConditions()).To(BeEmpty())
+		})
+
+		It("should return the conditions observed for an older generation", func() {
+			snapshot.Generation = 2
+			snapshot.Status.Conditions = []metav1.Condition{
+				{Type: ApplicationSnapshotConditionTypeSucceeded, ObservedGeneration: 1},
+				{Type: ApplicationSnapshotConditionTypeValidated, ObservedGeneration: 2},
+				{Type: ApplicationSnapshotConditionTypeReleased},
+			}
+
+			Expect(snapshot.StaleConditions()).To(Equal([]string{
+				ApplicationSnapshotConditionTypeSucceeded,
+				ApplicationSnapshotConditionTypeReleased,
+			}))
+		})
+	})
+
+	Context("Testing ReconcileRequest and ReconcileRequests", func() {
+
+		It("should return a request for the snapshot namespaced name", func() {
+			request := snapshot.ReconcileRequest()
+			Expect(request.NamespacedName).To(Equal(types.NamespacedName{Namespace: "my-namespace", Name: "my-snapshot"}))
+		})
+
+		It("should return a request for each snapshot of the list", func() {
+			other := snapshot.DeepCopy()
+			other.Name = "other-snapshot"
+			list := &ApplicationSnapshotList{Items: []ApplicationSnapshot{*snapshot, *other}}
+
+			requests := list.ReconcileRequests()
+			Expect(requests).To(HaveLen(2))
+			Expect(requests[0].NamespacedName).To(Equal(types.NamespacedName{Namespace: "my-namespace", Name: "my-snapshot"}))
+			Expect(requests[1].NamespacedName).To(Equal(types.NamespacedName{Namespace: "my-namespace", Name: "other-snapshot"}))
+
+			Expect((&ApplicationSnapshotList{}).ReconcileRequests()).To(BeEmpty())
+		})
+	})
+
+	Context("Testing ConditionDiff", func() {
+
+		It("should report every condition as new without a previous snapshot", func() {
+			snapshot.MarkRunning()
+
+			Expect(snapshot.ConditionDiff(nil)).To(Equal([]ConditionChange{{
+				Type:      ApplicationSnapshotConditionTypeSucceeded,
+				NewStatus: metav1.ConditionUnknown,
+				NewReason: ApplicationSnapshotReasonTestsRunning.String(),
+			}}))
+		})
+
+		It("should report no changes for identical conditions", func() {
+			snapshot.MarkRunning()
+			Expect(snapshot.ConditionDiff(snapshot.DeepCopy())).To(BeEmpty())
+		})
+
+		It("should report a status transition", func() {
+			snapshot.MarkRunning()
+			previous := snapshot.DeepCopy()
+			snapshot.MarkSucceeded()
+
+			Expect(snapshot.ConditionDiff(previous)).To(Equal([]ConditionChange{{
+				Type:      ApplicationSnapshotConditionTypeSucceeded,
+				OldStatus: metav1.ConditionUnknown,
+				NewStatus: metav1.ConditionTrue,
+				OldReason: ApplicationSnapshotReasonTestsRunning.String(),
+				NewReason: ApplicationSnapshotReasonSucceeded.String(),
+			}}))
+		})
+
+		It("should report a reason-only change", func() {
+			snapshot.MarkInvalid(ApplicationSnapshotReasonValidationError, "invalid")
+			previous := snapshot.DeepCopy()
+			snapshot.Status.Conditions[0].Reason = ApplicationSnapshotReasonTestsFailed.String()
+
+			changes := snapshot.ConditionDiff(previous)
+			Expect(changes).To(HaveLen(1))
+			Expect(changes[0].OldStatus).To(Equal(metav1.ConditionFalse))
+			Expect(changes[0].NewStatus).To(Equal(metav1.ConditionFalse))
+			Expect(changes[0].OldReason).To(Equal(ApplicationSnapshotReasonValidationError.String()))
+			Expect(changes[0].NewReason).To(Equal(ApplicationSnapshotReasonTestsFailed.String()))
+		})
+
+		It("should report a removed condition", func() {
+			previous := snapshot.DeepCopy()
+			previous.MarkRunning()
+
+			Expect(snapshot.ConditionDiff(previous)).To(Equal([]ConditionChange{{
+				Type:      ApplicationSnapshotConditionTypeSucceeded,
+				OldStatus: metav1.ConditionUnknown,
+				OldReason: ApplicationSnapshotReasonTestsRunning.String(),
+			}}))
+		})
+	})
+
+	Context("Testing NormalizeType", func() {
+
+		It("should lowercase the supported types", func() {
+			snapshot.Spec.Type = "Component"
+			snapshot.NormalizeType()
+			Expect(snapshot.Spec.Type).To(Equal(ApplicationSnapshotTypeComponent))
+
+			snapshot.Spec.Type = " COMPOSITE "
+			snapshot.NormalizeType()
+			Expect(snapshot.Spec.Type).To(Equal(ApplicationSnapshotTypeComposite))
+		})
+
+		It("should map the legacy aliases to the supported types", func() {
+			aliases := map[string]string{
+				"Single":           ApplicationSnapshotTypeComponent,
+				"single-component": ApplicationSnapshotTypeComponent,
+				"Application":      ApplicationSnapshotTypeComposite,
+				"multi-component":  ApplicationSnapshotTypeComposite,
+			}
+
+			for alias, supportedType := range aliases {
+				snapshot.Spec.Type = alias
+				snapshot.NormalizeType()
+				Expect(snapshot.Spec.Type).To(Equal(supportedType))
+			}
+		})
+
+		It("should leave empty and unknown types untouched", func() {
+			snapshot.NormalizeType()
+			Expect(snapshot.Spec.Type).To(BeEmpty())
+
+			snapshot.Spec.Type = "Unknown-Type"
+			snapshot.NormalizeType()
+			Expect(snapshot.Spec.Type).To(Equal("Unknown-Type"))
+		})
+	})
+
+	Context("Testing HasAllConditions", func() {
+
+		BeforeEach(func() {
+			snapshot.Status.Conditions = []metav1.Condition{
+				{Type: ApplicationSnapshotConditionTypeSucceeded, Status: metav1.ConditionFalse},
+				{Type: ApplicationSnapshotConditionTypeValidated, Status: metav1.ConditionTrue},
+			}
+		})
+
+		It("should be true when every condition is present, regardless of its status", func() {
+			Expect(snapshot.HasAllConditions(ApplicationSnapshotConditionTypeSucceeded, ApplicationSnapshotConditionTypeValidated)).To(BeTrue())
+			Expect(snapshot.HasAllConditions(ApplicationSnapshotConditionTypeSucceeded)).To(BeTrue())
+			Expect(snapshot.HasAllConditions()).To(BeTrue())
+		})
+
+		It("should be false when a condition is missing", func() {
+			Expect(snapshot.HasAllConditions(AllConditionTypes()...)).To(BeFalse())
+			Expect((&ApplicationSnapshot{}).HasAllConditions(ApplicationSnapshotConditionTypeSucceeded)).To(BeFalse())
+		})
+	})
+
+	Context("Testing PageKey", func() {
+
+		It("should format the creation timestamp, namespace and name", func() {
+			snapshot.CreationTimestamp = metav1.NewTime(time.Date(2022, time.May, 3, 10, 4, 5, 0, time.FixedZone("EST", -5*60*60)))
+
+			Expect(snapshot.PageKey()).To(Equal("2022-05-03T15:04:05Z|my-namespace|my-snapshot"))
+		})
+
+		It("should sort lexically in creation order", func() {
+			now := time.Now()
+			snapshots := []ApplicationSnapshot{}
+			for i, age := range []time.Duration{time.Second, 10 * time.Hour, 0, time.Hour, 400 * 24 * time.Hour} {
+				s := snapshot.DeepCopy()
+				s.Name = fmt.Sprintf("snapshot-%d", i)
+				s.CreationTimestamp = metav1.NewTime(now.Add(-age))
+				snapshots = append(snapshots, *s)
+			}
+			tied := snapshot.DeepCopy()
+			tied.Namespace = "a-namespace"
+			tied.CreationTimestamp = snapshots[2].CreationTimestamp
+			snapshots = append(snapshots, *tied)
+
+			keys := []string{}
+			keyNames := map[string]string{}
+			for _, s := range snapshots {
+				keys = append(keys, s.PageKey())
+				keyNames[s.PageKey()] = s.Namespace + "/" + s.Name
+			}
+			sort.Strings(keys)
+
+			names := []string{}
+			for _, key := range keys {
+				names = append(names, keyNames[key])
+			}
+			Expect(names).To(Equal([]string{
+				"my-namespace/snapshot-4",
+				"my-namespace/snapshot-1",
+				"my-namespace/snapshot-3",
+				"my-namespace/snapshot-0",
+				"a-namespace/my-snapshot",
+				"my-namespace/snapshot-2",
+			}))
+		})
+	})
+
+	Context("Testing ValidateImagesAllowed", func() {
+
+		It("should allow every image without patterns", func() {
+			Expect(snapshot.ValidateImagesAllowed(nil)).To(Succeed())
+			Expect(snapshot.ValidateImagesAllowed([]*regexp.Regexp{})).To(Succeed())
+		})
+
+		It("should accept images matching an allow pattern", func() {
+			patterns := []*regexp.Regexp{
+				regexp.MustCompile(`^registry\.example\.com/`),
+				regexp.MustCompile(`^quay\.io/org/`),
+			}
+			Expect(snapshot.ValidateImagesAllowed(patterns)).To(Succeed())
+		})
+
+		It("should list the components whose image matches no allow pattern", func() {
+			snapshot.Spec.Components[1].ContainerImage = "docker.io/org/component-b:v1"
+			patterns := []*regexp.Regexp{regexp.MustCompile(`^quay\.io/org/`)}
+
+			err := snapshot.ValidateImagesAllowed(patterns)
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(ContainSubstring("spec.components[1].containerImage"))
+			Expect(err.Error()).To(ContainSubstring("image of component component-b does not match any of the allowed image patterns"))
+			Expect(err.Error()).ToNot(ContainSubstring("spec.components[0]"))
+		})
+	})
+
+	Context("Testing ForceMarkFailed", func() {
+
+		BeforeEach(func() {
+			snapshot.MarkRunning()
+			snapshot.MarkSucceeded()
+			snapshot.Status.StartTime = &metav1.Time{Time: time.Now().Add(-time.Hour)}
+			snapshot.Status.CompletionTime = &metav1.Time{Time: snapshot.Status.StartTime.Add(time.Minute)}
+		})
+
+		It("should not be overridden by MarkFailed", func() {
+			snapshot.MarkFailed(ApplicationSnapshotReasonTestsFailed, "vulnerability found")
+			Expect(snapshot.HasSucceeded()).To(BeTrue())
+		})
+
+		It("should override a succeeded snapshot", func() {
+			previousCompletionTime := snapshot.Status.CompletionTime
+
+			snapshot.ForceMarkFailed(ApplicationSnapshotReasonTestsFailed, "vulnerability found")
+
+			Expect(snapshot.HasSucceeded()).To(BeFalse())
+			condition := meta.FindStatusCondition(snapshot.Status.Conditions, ApplicationSnapshotConditionTypeSucceeded)
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal(ApplicationSnapshotReasonTestsFailed.String()))
+			Expect(condition.Message).To(Equal("vulnerability found"))
+			Expect(snapshot.Status.CompletionTime.After(previousCompletionTime.Time)).To(BeTrue())
+			Expect(snapshot.Status.Phase).To(Equal(ApplicationSnapshotPhaseFailed))
+		})
+
+		It("should record the override annotation", func() {
+			snapshot.ForceMarkFailed(ApplicationSnapshotReasonTestsFailed, "vulnerability found")
+
+			Expect(snapshot.Annotations).To(HaveKeyWithValue(ApplicationSnapshotStatusOverriddenAnnotation, "true"))
+		})
+	})
+
+	Context("Testing ComponentTag", func() {
+
+		digest := "sha256:" + strings.Repeat("a", 64)
+
+		It("should return the tag of a tag-only reference", func() {
+			tag, exists := snapshot.ComponentTag("component-a")
+			Expect(exists).To(BeTrue())
+			Expect(tag).To(Equal("v1"))
+		})
+
+		It("should not return a tag for a digest-only reference", func() {
+			snapshot.Spec.Components[0].ContainerImage = "quay.io/org/component-a@" + digest
+
+			_, exists := snapshot.ComponentTag("component-a")
+			Expect(exists).To(BeFalse())
+		})
+
+		It("should return the tag of a tag and digest reference", func() {
+			snapshot.Spec.Components[0].ContainerImage = "quay.io/org/component-a:v2@" + digest
+
+			tag, exists := snapshot.ComponentTag("component-a")
+			Expect(exists).To(BeTrue())
+			Expect(tag).To(Equal("v2"))
+		})
+
+		It("should not return a tag for an unknown component or an invalid image", func() {
+			_, exists := snapshot.ComponentTag("component-c")
+			Expect(exists).To(BeFalse())
+
+			snapshot.Spec.Components[0].ContainerImage = "Not An Image"
+			_, exists = snapshot.ComponentTag("component-a")
+			Expect(exists).To(BeFalse())
+		})
+	})
+
+	Context("Testing InFreezeWindow", func() {
+
+		now := time.Date(2022, time.December, 24, 12, 0, 0, 0, time.UTC)
+		windows := []FreezeWindow{
+			{Start: now.Add(-time.Hour), End: now.Add(time.Hour)},
+			{Start: now, End: now.Add(2 * time.Hour)},
+		}
+
+		It("should be in a freeze window inside any of the windows", func() {
+			Expect(snapshot.InFreezeWindow(windows, now.Add(-time.Minute))).To(BeTrue())
+			Expect(snapshot.InFreezeWindow(windows, now.Add(90*time.Minute))).To(BeTrue())
+		})
+
+		It("should not be in a freeze window outside all the windows", func() {
+			Expect(snapshot.InFreezeWindow(windows, now.Add(-2*time.Hour))).To(BeFalse())
+			Expect(snapshot.InFreezeWindow(windows, now.Add(3*time.Hour))).To(BeFalse())
+			Expect(snapshot.InFreezeWindow(nil, now)).To(BeFalse())
+		})
+
+		It("should include the start time but not the end time of a window", func() {
+			Expect(snapshot.InFreezeWindow(windows, now.Add(-time.Hour))).To(BeTrue())
+			Expect(snapshot.InFreezeWindow(windows, now.Add(2*time.Hour))).To(BeFalse())
+		})
+
+		It("should block the automatic release during a freeze window", func() {
+			snapshot.MarkRunning()
+			snapshot.MarkSucceeded()
+			policy := AutoReleasePolicy{
+				FreezeWindows: []FreezeWindow{{Start: time.Now().Add(-time.Hour), End: time.Now().Add(time.Hour)}},
+			}
+
+			eligible, reason := snapshot.AutoReleaseEligible(policy)
+			Expect(eligible).To(BeFalse())
+			Expect(reason).To(Equal("a change freeze window is in effect"))
+		})
+	})
+
+	Context("Testing ConditionsEventPayload", func() {
+
+		It("should describe the snapshot conditions", func() {
+			snapshot.MarkRunning()
+
+			payload, err := snapshot.ConditionsEventPayload()
+			Expect(err).To(BeNil())
+
+			document := map[string]interface{}{}
+			Expect(json.Unmarshal(payload, &document)).To(Succeed())
+			Expect(document).To(HaveLen(5))
+			Expect(document).To(HaveKeyWithValue("name", "my-snapshot"))
+			Expect(document).To(HaveKeyWithValue("namespace", "my-namespace"))
+			Expect(document).To(HaveKeyWithValue("application", "my-app"))
+			Expect(document).To(HaveKeyWithValue("phase", ApplicationSnapshotPhaseRunning))
+			Expect(document["conditions"]).To(HaveLen(1))
+		})
+
+		It("should omit the spec internals and be compact", func() {
+			payload, err := snapshot.ConditionsEventPayload()
+			Expect(err).To(BeNil())
+
+			Expect(string(payload)).ToNot(ContainSubstring("component-a"))
+			Expect(string(payload)).ToNot(ContainSubstring("spec"))
+			Expect(string(payload)).ToNot(ContainSubstring("\n"))
+			Expect(string(payload)).To(ContainSubstring(`"conditions":[]`))
+		})
+	})
+
+	Context("Testing ApplicationSnapshotList.UnionComponents", func() {
+
+		It("should return nothing for an empty list", func() {
+			Expect((&ApplicationSnapshotList{}).UnionComponents()).To(BeEmpty())
+		})
+
+		It("should merge overlapping and distinct component sets", func() {
+			updated := snapshot.DeepCopy()
+			updated.Spec.Components = []ApplicationSnapshotComponent{
+				{Name: "component-a", ContainerImage: "quay.io/org/component-a:v2"},
+				{Name: "component-b", ContainerImage: "quay.io/org/component-b:v1"},
+				{Name: "component-c", ContainerImage: "quay.io/org/component-c:v1"},
+			}
+			list := &ApplicationSnapshotList{Items: []ApplicationSnapshot{*updated, *snapshot}}
+
+			Expect(list.UnionComponents()).To(Equal(map[string][]string{
+				"component-a": {"quay.io/org/component-a:v1", "quay.io/org/component-a:v2"},
+				"component-b": {"quay.io/org/component-b:v1"},
+				"component-c": {"quay.io/org/component-c:v1"},
+			}))
+		})
+	})
+
+	Context("Testing AddBinding and BindingsForEnvironment", func() {
+
+		It("should record and query the bindings of each environment", func() {
+			Expect(snapshot.AddBinding("staging", "my-namespace/staging-binding")).To(Succeed())
+			Expect(snapshot.AddBinding("staging", "my-namespace/other-staging-binding")).To(Succeed())
+			Expect(snapshot.AddBinding("production", "my-namespace/production-binding")).To(Succeed())
+
+			Expect(snapshot.BindingsForEnvironment("staging")).To(Equal([]string{
+				"my-namespace/staging-binding",
+				"my-namespace/other-staging-binding",
+			}))
+			Expect(snapshot.BindingsForEnvironment("production")).To(Equal([]string{"my-namespace/production-binding"}))
+			Expect(snapshot.BindingsForEnvironment("development")).To(BeEmpty())
+		})
+
+		It("should record each binding only once", func() {
+			Expect(snapshot.AddBinding("staging", "my-namespace/staging-binding")).To(Succeed())
+			Expect(snapshot.AddBinding("staging", "my-namespace/staging-binding")).To(Succeed())
+
+			Expect(snapshot.Status.Bindings).To(Equal([]BindingRef{{Environment: "staging", Binding: "my-namespace/staging-binding"}}))
+		})
+
+		It("should reject a binding which is not a namespaced name", func() {
+			err := snapshot.AddBinding("staging", "staging-binding")
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(ContainSubstring("is not a valid namespaced name"))
+			Expect(snapshot.Status.Bindings).To(BeEmpty())
+		})
+	})
+
+	Context("Testing the condition LastTransitionTime guarantees", func() {
+
+		It("should set the LastTransitionTime of an existing condition without one", func() {
+			snapshot.Status.Conditions = []metav1.Condition{
+				{Type: ApplicationSnapshotConditionTypeSucceeded, Status: metav1.ConditionUnknown, Reason: "TestsRunning"},
+			}
+
+			snapshot.MarkRunning()
+			Expect(snapshot.Status.Conditions[0].LastTransitionTime.IsZero()).To(BeFalse())
+		})
+
+		It("should accept a condition with a well-formed LastTransitionTime", func() {
+			snapshot.MarkInvalid(ApplicationSnapshotReasonValidationError, "invalid")
+			Expect(snapshot.ValidateStatusConsistency()).To(Succeed())
+		})
+
+		It("should reject a condition with a zero LastTransitionTime", func() {
+			snapshot.MarkInvalid(ApplicationSnapshotReasonValidationError, "invalid")
+			snapshot.Status.Conditions = append(snapshot.Status.Conditions,
+				metav1.Condition{Type: ApplicationSnapshotConditionTypeValidated, Status: metav1.ConditionFalse})
+
+			err := snapshot.ValidateStatusConsistency()
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(ContainSubstring("status.conditions[1].lastTransitionTime"))
+		})
+	})
+
+	Context("Testing NeedsRevalidation", func() {
+
+		BeforeEach(func() {
+			snapshot.Generation = 2
+		})
+
+		It("should need revalidation without a Validated condition", func() {
+			snapshot.MarkRunning()
+			Expect(snapshot.NeedsRevalidation()).To(BeTrue())
+		})
+
+		It("should need revalidation when the Validated condition is stale", func() {
+			snapshot.Status.Conditions = []metav1.Condition{
+				{Type: ApplicationSnapshotConditionTypeValidated, Status: metav1.ConditionTrue, ObservedGeneration: 1},
+			}
+			Expect(snapshot.NeedsRevalidation()).To(BeTrue())
+		})
+
+		It("should not need revalidation when the Validated condition is current", func() {
+			snapshot.Status.Conditions = []metav1.Condition{
+				{Type: ApplicationSnapshotConditionTypeValidated, Status: metav1.ConditionTrue, ObservedGeneration: 2},
+			}
+			Expect(snapshot.NeedsRevalidation()).To(BeFalse())
+		})
+	})
+
+	Context("Testing RestoreSpecPatch", func() {
+
+		It("should only contain spec fields and restore the target spec", func() {
+			target := snapshot.DeepCopy()
+			target.Name = "my-previous-snapshot"
+			target.Spec.Components = []ApplicationSnapshotComponent{
+				{Name: "component-a", ContainerImage: "quay.io/org/component-a:v0"},
+			}
+			snapshot.Spec.DisplayName = "My snapshot"
+			snapshot.MarkRunning()
+
+			patch, err := snapshot.RestoreSpecPatch(target)
+			Expect(err).To(BeNil())
+
+			parsed := map[string]interface{}{}
+			Expect(json.Unmarshal(patch, &parsed)).To(Succeed())
+			Expect(parsed).To(HaveKey("spec"))
+			Expect(parsed).To(HaveLen(1))
+
+			original, err := json.Marshal(snapshot)
+			Expect(err).To(BeNil())
+			patched, err := jsonpatch.MergePatch(original, patch)
+			Expect(err).To(BeNil())
+
+			restored := &ApplicationSnapshot{}
+			Expect(json.Unmarshal(patched, restored)).To(Succeed())
+			Expect(restored.Spec).To(Equal(target.Spec))
+			Expect(restored.Name).To(Equal(snapshot.Name))
+			Expect(restored.Status.Conditions).To(HaveLen(1))
+		})
+
+		It("should fail without a target snapshot", func() {
+			_, err := snapshot.RestoreSpecPatch(nil)
+			Expect(err).ToNot(BeNil())
+		})
+	})
+
+	Context("Testing ElapsedSinceStart", func() {
+
+		var start time.Time
+
+		BeforeEach(func() {
+			start = time.Date(2022, time.May, 1, 12, 0, 0, 0, time.UTC)
+			snapshot.MarkRunning()
+			snapshot.Status.StartTime = &metav1.Time{Time: start}
+		})
+
+		It("should format a sub-minute duration", func() {
+			elapsed, ok := snapshot.ElapsedSinceStart(start.Add(45*time.Second + 300*time.Millisecond))
+			Expect(ok).To(BeTrue())
+			Expect(elapsed).To(Equal("45s"))
+		})
+
+		It("should format a multi-minute duration", func() {
+			elapsed, ok := snapshot.ElapsedSinceStart(start.Add(3*time.Minute + 20*time.Second))
+			Expect(ok).To(BeTrue())
+			Expect(elapsed).To(Equal("3m20s"))
+		})
+
+		It("should not report an elapsed time when not started", func() {
+			snapshot.Status.StartTime = nil
+
+			elapsed, ok := snapshot.ElapsedSinceStart(start)
+			Expect(ok).To(BeFalse())
+			Expect(elapsed).To(BeEmpty())
+		})
+	})
+
+	Context("Testing ApplicationSnapshotReason IsTerminal", func() {
+
+		It("should classify every defined reason", func() {
+			for reason, terminal := range map[ApplicationSnapshotReason]bool{
+				ApplicationSnapshotReasonInitialized:     false,
+				ApplicationSnapshotReasonTestsRunning:    false,
+				ApplicationSnapshotReasonSucceeded:       true,
+				ApplicationSnapshotReasonTestsFailed:     true,
+				ApplicationSnapshotReasonValidationError: true,
+				ApplicationSnapshotReasonCancelled:       true,
+				ApplicationSnapshotReasonSkipped:         true,
+				ApplicationSnapshotReasonTimedOut:        true,
+			} {
+				Expect(reason.IsTerminal()).To(Equal(terminal), "reason %s", reason)
+			}
+		})
+
+		It("should guard the Mark functions on terminal reasons", func() {
+			snapshot.MarkRunning()
+			snapshot.MarkFailed(ApplicationSnapshotReasonTimedOut, "tests timed out")
+
+			snapshot.MarkSucceeded()
+			snapshot.MarkSkipped("tests skipped")
+			snapshot.MarkInvalid(ApplicationSnapshotReasonValidationError, "invalid")
+			_, reason, _ := snapshot.TestOutcome()
+			Expect(reason).To(Equal(ApplicationSnapshotReasonTimedOut))
+		})
+
+		It("should guard the Mark functions on terminal reasons without a completion time", func() {
+			snapshot.Status.Conditions = []metav1.Condition{{
+				Type:   ApplicationSnapshotConditionTypeSucceeded,
+				Status: metav1.ConditionFalse,
+				Reason: ApplicationSnapshotReasonCancelled.String(),
+			}}
+
+			snapshot.MarkFailed(ApplicationSnapshotReasonTestsFailed, "tests failed")
+			_, reason, _ := snapshot.TestOutcome()
+			Expect(reason).To(Equal(ApplicationSnapshotReasonCancelled))
+			Expect(snapshot.Status.CompletionTime).To(BeNil())
+		})
+
+		It("should let the Mark functions override a non-terminal reason", func() {
+			snapshot.Status.Conditions = []metav1.Condition{{
+				Type:   ApplicationSnapshotConditionTypeSucceeded,
+				Status: metav1.ConditionUnknown,
+				Reason: ApplicationSnapshotReasonInitialized.String(),
+			}}
+
+			snapshot.MarkSucceeded()
+			Expect(snapshot.HasSucceeded()).To(BeTrue())
+		})
+
+		It("should not let the Mark functions override a failure with a custom reason", func() {
+			snapshot.MarkRunning()
+			snapshot.MarkFailed("RegistryOutage", "registry unavailable")
+
+			snapshot.MarkSucceeded()
+			snapshot.MarkSkipped("tests skipped")
+			Expect(snapshot.HasSucceeded()).To(BeFalse())
+			Expect(snapshot.Phase()).To(Equal(ApplicationSnapshotPhaseFailed))
+			_, reason, _ := snapshot.TestOutcome()
+			Expect(reason).To(Equal(ApplicationSnapshotReason("RegistryOutage")))
+		})
+
+		It("should not let MarkRunning reopen a finished ApplicationSnapshot", func() {
+			snapshot.MarkInvalid(ApplicationSnapshotReasonValidationError, "invalid")
+
+			snapshot.MarkRunning()
+			Expect(snapshot.Phase()).To(Equal(ApplicationSnapshotPhaseInvalid))
+			Expect(snapshot.Status.StartTime).To(BeNil())
+		})
+
+		It("should not consider an unknown reason terminal", func() {
+			Expect(ApplicationSnapshotReason("SomethingElse").IsTerminal()).To(BeFalse())
+		})
+	})
+
+	Context("Testing ArtifactsDiff", func() {
+
+		var previous *ApplicationSnapshot
+
+		BeforeEach(func() {
+			previous = snapshot.DeepCopy()
+			previous.Spec.Artifacts.UnstableFields = &apiextensionsv1.JSON{Raw: []byte(`{"kept": 1, "changed": "old", "removed": true}`)}
+			previous.Spec.Artifacts.Images = []ImageSource{
+				{Component: "component-a", URL: "https://github.com/org/component-a", Revision: "abc123"},
+			}
+		})
+
+		It("should report the added, removed and changed artifact keys", func() {
+			snapshot.Spec.Artifacts.UnstableFields = &apiextensionsv1.JSON{Raw: []byte(`{"kept":1,"changed":"new","added":[1,2]}`)}
+			snapshot.Spec.Artifacts.Images = []ImageSource{
+				{Component: "component-a", URL: "https://github.com/org/component-a", Revision: "def456"},
+				{Component: "component-b", URL: "https://github.com/org/component-b", Revision: "123abc"},
+			}
+
+			diff, err := snapshot.ArtifactsDiff(previous)
+			Expect(err).To(BeNil())
+			Expect(diff).To(MatchJSON(`{
+				"unstableFields": {
+					"added": {"added": [1, 2]},
+					"removed": {"removed": true},
+					"changed": {"changed": {"previous": "old", "current": "new"}}
+				},
+				"images": {
+					"added": {"component-b": {"component": "component-b", "url": "https://github.com/org/component-b", "revision": "123abc"}},
+					"changed": {"component-a": {
+						"previous": {"component": "component-a", "url": "https://github.com/org/component-a", "revision": "abc123"},
+						"current": {"component": "component-a", "url": "https://github.com/org/component-a", "revision": "def456"}
+					}}
+				}
+			}`))
+
+			again, err := snapshot.ArtifactsDiff(previous)
+			Expect(err).To(BeNil())
+			Expect(again).To(Equal(diff))
+		})
+
+		It("should report removed artifacts when they are all gone", func() {
+			diff, err := snapshot.ArtifactsDiff(previous)
+			Expect(err).To(BeNil())
+			Expect(diff).To(MatchJSON(`{
+				"unstableFields": {"removed": {"kept": 1, "changed": "old", "removed": true}},
+				"images": {"removed": {"component-a": {"component": "component-a", "url": "https://github.com/org/component-a", "revision": "abc123"}}}
+			}`))
+		})
+
+		It("should report no changes for identical artifacts", func() {
+			diff, err := previous.DeepCopy().ArtifactsDiff(previous)
+			Expect(err).To(BeNil())
+			Expect(diff).To(MatchJSON(`{"unstableFields": {}, "images": {}}`))
+		})
+
+		It("should fail for unstableFields which aren't a JSON object", func() {
+			snapshot.Spec.Artifacts.UnstableFields = &apiextensionsv1.JSON{Raw: []byte(`[1, 2]`)}
+
+			_, err := snapshot.ArtifactsDiff(previous)
+			Expect(err).ToNot(BeNil())
+		})
+	})
+
+	Context("Testing WithUpdatedComponentImage", func() {
+
+		BeforeEach(func() {
+			snapshot.ResourceVersion = "42"
+			snapshot.Labels = map[string]string{"team": "frontend"}
+			snapshot.Annotations = map[string]string{
+				"team.example.com/owner":                      "frontend",
+				ApplicationSnapshotApprovedAnnotation:         "true",
+				ApplicationSnapshotStatusOwnerAnnotation:      "integration-service",
+				ApplicationSnapshotStatusOverriddenAnnotation: "true",
+				ApplicationSnapshotSupersededByAnnotation:     "my-namespace/other-snapshot",
+				ApplicationSnapshotRollbackTargetAnnotation:   "previous-snapshot",
+			}
+			snapshot.MarkRunning()
+		})
+
+		It("should bump the image of a component in a fresh copy", func() {
+			updated, err := snapshot.WithUpdatedComponentImage("component-b", "quay.io/org/component-b:v2")
+			Expect(err).To(BeNil())
+
+			Expect(updated.Name).To(BeEmpty())
+			Expect(updated.GenerateName).To(Equal("my-app-"))
+			Expect(updated.Namespace).To(Equal(snapshot.Namespace))
+			Expect(updated.ResourceVersion).To(BeEmpty())
+			Expect(updated.Labels).To(Equal(snapshot.Labels))
+			Expect(updated.Annotations).To(Equal(map[string]string{"team.example.com/owner": "frontend"}))
+			Expect(updated.Annotations).ToNot(HaveKey(ApplicationSnapshotApprovedAnnotation))
+			Expect(updated.Status).To(Equal(ApplicationSnapshotStatus{}))
+			Expect(updated.Spec.Components).To(Equal([]ApplicationSnapshotComponent{
+				{Name: "component-a", ContainerImage: "quay.io/org/component-a:v1"},
+				{Name: "component-b", ContainerImage: "quay.io/org/component-b:v2"},
+			}))
+
+			Expect(snapshot.Spec.Components[1].ContainerImage).To(Equal("quay.io/org/component-b:v1"))
+
+			By("not sharing the labels and annotations with the original snapshot")
+			updated.Labels["team"] = "backend"
+			updated.Annotations["team.example.com/owner"] = "backend"
+			Expect(snapshot.Labels["team"]).To(Equal("frontend"))
+			Expect(snapshot.Annotations["team.example.com/owner"]).To(Equal("frontend"))
+		})
+
+		It("should require the bumped snapshot to be approved again", func() {
+			policy := AutoReleasePolicy{RequireApproval: true}
+			snapshot.MarkSucceeded()
+			eligible, _ := snapshot.AutoReleaseEligible(policy)
+			Expect(eligible).To(BeTrue())
+
+			updated, err := snapshot.WithUpdatedComponentImage("component-b", "quay.io/org/component-b:v2")
+			Expect(err).To(BeNil())
+			updated.MarkRunning()
+			updated.MarkSucceeded()
+			eligible, reason := updated.AutoReleaseEligible(policy)
+			Expect(eligible).To(BeFalse())
+			Expect(reason).To(Equal("the snapshot has not been approved"))
+		})
+
+		It("should fail for a snapshot without an application", func() {
+			snapshot.Spec.Application = ""
+
+			updated, err := snapshot.WithUpdatedComponentImage("component-b", "quay.io/org/component-b:v2")
+			Expect(err).ToNot(BeNil())
+			Expect(updated).To(BeNil())
+		})
+
+		It("should fail for a missing component", func() {
+			updated, err := snapshot.WithUpdatedComponentImage("component-c", "quay.io/org/component-c:v1")
+			Expect(err).ToNot(BeNil())
+			Expect(updated).To(BeNil())
+		})
+
+		It("should fail for an invalid image", func() {
+			_, err := snapshot.WithUpdatedComponentImage("component-b", "quay.io/org/Component-B:v2")
+			Expect(err).ToNot(BeNil())
+		})
+	})
+
+	Context("Testing EstimatedSize and ValidateSizeBudget", func() {
+
+		const fixtureSize = 100 * 1024
+
+		BeforeEach(func() {
+			// A JSON string of fixtureSize bytes, including its quotes
+			blob := `{"blob":"` + strings.Repeat("a", fixtureSize-len(`{"blob":""}`)) + `"}`
+			snapshot.Spec.Artifacts.UnstableFields = &apiextensionsv1.JSON{Raw: []byte(blob)}
+		})
+
+		It("should estimate the size of a known-size fixture within a tolerance", func() {
+			size := snapshot.EstimatedSize()
+			Expect(size).To(BeNumerically(">", fixtureSize))
+			Expect(size).To(BeNumerically("<", fixtureSize+1024))
+		})
+
+		It("should accept a snapshot within the size budget", func() {
+			Expect(snapshot.ValidateSizeBudget(MaxApplicationSnapshotSize)).To(Succeed())
+		})
+
+		It("should reject a snapshot over the size budget", func() {
+			err := snapshot.ValidateSizeBudget(fixtureSize)
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(ContainSubstring("over the size budget of 102400 bytes"))
+		})
+	})
+
+	Context("Testing ConditionFresh", func() {
+
+		var transition time.Time
+
+		BeforeEach(func() {
+			snapshot.MarkRunning()
+			transition = time.Date(2022, time.May, 1, 12, 0, 0, 0, time.UTC)
+			snapshot.Status.Conditions[0].LastTransitionTime = metav1.Time{Time: transition}
+		})
+
+		It("should be fresh within the max age", func() {
+			Expect(snapshot.ConditionFresh(time.Hour, transition.Add(59*time.Minute))).To(BeTrue())
+		})
+
+		It("should be fresh exactly at the max age", func() {
+			Expect(snapshot.ConditionFresh(time.Hour, transition.Add(time.Hour))).To(BeTrue())
+		})
+
+		It("should not be fresh past the max age", func() {
+			Expect(snapshot.ConditionFresh(time.Hour, transition.Add(time.Hour+time.Second))).To(BeFalse())
+		})
+
+		It("should not be fresh without a Succeeded condition", func() {
+			snapshot.Status.Conditions = nil
+			Expect(snapshot.ConditionFresh(time.Hour, transition)).To(BeFalse())
+		})
+	})
+
+	Context("Testing ApplicationSnapshotList WithPhase", func() {
+
+		var list *ApplicationSnapshotList
+
+		BeforeEach(func() {
+			pending := snapshot.DeepCopy()
+			pending.Name = "pending"
+			running := snapshot.DeepCopy()
+			running.Name = "running"
+			running.MarkRunning()
+			failed := snapshot.DeepCopy()
+			failed.Name = "failed"
+			failed.MarkRunning()
+			failed.MarkFailed(ApplicationSnapshotReasonTestsFailed, "tests failed")
+			succeeded := snapshot.DeepCopy()
+			succeeded.Name = "succeeded"
+			succeeded.MarkRunning()
+			succeeded.MarkSucceeded()
+
+			list = &ApplicationSnapshotList{Items: []ApplicationSnapshot{*pending, *running, *failed, *succeeded}}
+		})
+
+		names := func(snapshots []ApplicationSnapshot) []string {
+			result := []string{}
+			for _, s := range snapshots {
+				result = append(result, s.Name)
+			}
+			return result
+		}
+
+		It("should filter on a single phase", func() {
+			Expect(names(list.WithPhase(ApplicationSnapshotPhaseFailed))).To(Equal([]string{"failed"}))
+		})
+
+		It("should filter on multiple phases", func() {
+			Expect(names(list.WithPhase(ApplicationSnapshotPhaseRunning, ApplicationSnapshotPhaseSucceeded))).
+				To(Equal([]string{"running", "succeeded"}))
+		})
+
+		It("should return all the snapshots without phases", func() {
+			Expect(names(list.WithPhase())).To(Equal([]string{"pending", "running", "failed", "succeeded"}))
+		})
+	})
+
+	Context("Testing SetSupersededBy and GetSupersededBy", func() {
+
+		It("should not be superseded initially", func() {
+			_, exists := snapshot.GetSupersededBy()
+			Expect(exists).To(BeFalse())
+		})
+
+		It("should record and return the superseding snapshot", func() {
+			Expect(snapshot.SetSupersededBy("my-namespace/newer-snapshot")).To(Succeed())
+
+			nn, exists := snapshot.GetSupersededBy()
+			Expect(exists).To(BeTrue())
+			Expect(nn).To(Equal("my-namespace/newer-snapshot"))
+			Expect(snapshot.Annotations).To(HaveKeyWithValue(ApplicationSnapshotSupersededByAnnotation, "my-namespace/newer-snapshot"))
+		})
+
+		It("should reject a value which is not a namespaced name", func() {
+			err := snapshot.SetSupersededBy("newer-snapshot")
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(ContainSubstring("is not a valid namespaced name"))
+			Expect(snapshot.Annotations).ToNot(HaveKey(ApplicationSnapshotSupersededByAnnotation))
+		})
+	})
+
+	Context("Testing ApplicationSnapshotList CancelSuperseded", func() {
+
+		It("should cancel the running snapshots superseded by the latest one", func() {
+			now := time.Now()
+			snapshot.CreationTimestamp = metav1.NewTime(now.Add(-time.Hour))
+			snapshot.MarkRunning()
+
+			otherApplication := snapshot.DeepCopy()
+			otherApplication.Name = "other-application"
+			otherApplication.Spec.Application = "other-app"
+
+			succeeded := snapshot.DeepCopy()
+			succeeded.Name = "succeeded"
+			succeeded.MarkSucceeded()
+
+			latest := snapshot.DeepCopy()
+			latest.Name = "latest"
+			latest.CreationTimestamp = metav1.NewTime(now)
+
+			list := &ApplicationSnapshotList{Items: []ApplicationSnapshot{*snapshot, *otherApplication, *succeeded, *latest}}
+			Expect(list.CancelSuperseded(latest)).To(Equal(1))
+
+			Expect(list.Items[0].IsDone()).To(BeTrue())
+			_, reason, _ := list.Items[0].TestOutcome()
+			Expect(reason).To(Equal(ApplicationSnapshotReasonCancelled))
+			nn, exists := list.Items[0].GetSupersededBy()
+			Expect(exists).To(BeTrue())
+			Expect(nn).To(Equal("my-namespace/latest"))
+
+			for _, item := range list.Items[1:] {
+				_, exists := item.GetSupersededBy()
+				Expect(exists).To(BeFalse())
+			}
+			Expect(list.Items[1].IsDone()).To(BeFalse())
+			Expect(list.Items[3].IsDone()).To(BeFalse())
+		})
+
+		It("should order the snapshots created in the same second by name", func() {
+			snapshot.CreationTimestamp = metav1.NewTime(time.Date(2022, time.May, 1, 12, 0, 0, 0, time.UTC))
+			snapshot.MarkRunning()
+
+			earlier := snapshot.DeepCopy()
+			earlier.Name = "my-snapshot-a"
+			later := snapshot.DeepCopy()
+			later.Name = "my-snapshot-c"
+			latest := snapshot.DeepCopy()
+			latest.Name = "my-snapshot-b"
+
+			list := &ApplicationSnapshotList{Items: []ApplicationSnapshot{*earlier, *later, *latest}}
+			Expect(list.CancelSuperseded(latest)).To(Equal(1))
+			Expect(list.Items[0].IsDone()).To(BeTrue())
+			Expect(list.Items[1].IsDone()).To(BeFalse())
+		})
+
+		It("should not cancel anything without a latest snapshot", func() {
+			snapshot.MarkRunning()
+			list := &ApplicationSnapshotList{Items: []ApplicationSnapshot{*snapshot}}
+
+			Expect(list.CancelSuperseded(nil)).To(BeZero())
+			Expect(list.Items[0].IsDone()).To(BeFalse())
+		})
+	})
+
+	Context("Testing MatchesDesired", func() {
+
+		var desired *ApplicationSnapshot
+
+		BeforeEach(func() {
+			snapshot.Spec.Type = ApplicationSnapshotTypeComposite
+			snapshot.Spec.DisplayName = "Renamed by a user"
+			snapshot.MarkRunning()
+
+			desired = &ApplicationSnapshot{
+				Spec: ApplicationSnapshotSpec{
+					Application: "my-app",
+					Type:        ApplicationSnapshotTypeComposite,
+					Components:  append([]ApplicationSnapshotComponent{}, snapshot.Spec.Components...),
+				},
+			}
+		})
+
+		It("should match a desired state with the same owned fields", func() {
+			Expect(snapshot.MatchesDesired(desired)).To(BeTrue())
+		})
+
+		It("should not match a desired state with different components", func() {
+			desired.Spec.Components[1].ContainerImage = "quay.io/org/component-b:v2"
+			Expect(snapshot.MatchesDesired(desired)).To(BeFalse())
+		})
+
+		It("should not match a desired state with a different type", func() {
+			desired.Spec.Type = ApplicationSnapshotTypeComponent
+			Expect(snapshot.MatchesDesired(desired)).To(BeFalse())
+		})
+
+		It("should compare the displayName only when it is owned", func() {
+			Expect(snapshot.MatchesDesired(desired, "application", "components", "type", "displayName")).To(BeFalse())
+
+			desired.Spec.DisplayName = "Renamed by a user"
+			Expect(snapshot.MatchesDesired(desired, "application", "components", "type", "displayName")).To(BeTrue())
+		})
+
+		It("should not match an unknown owned field", func() {
+			Expect(snapshot.MatchesDesired(desired, "unknown")).To(BeFalse())
+		})
+	})
+
+	Context("Testing ApplicationSnapshotList Collectible", func() {
+
+		var now time.Time
+		var list *ApplicationSnapshotList
+
+		newSnapshot := func(name, application string, age time.Duration, done bool) ApplicationSnapshot {
+			item := snapshot.DeepCopy()
+			item.Name = name
+			item.Spec.Application = application
+			item.CreationTimestamp = metav1.NewTime(now.Add(-age))
+			item.MarkRunning()
+			if done {
+				item.MarkSucceeded()
+				item.Status.CompletionTime = &metav1.Time{Time: now.Add(-age)}
+			}
+			return *item
+		}
+
+		names := func(snapshots []ApplicationSnapshot) []string {
+			result := []string{}
+			for _, s := range snapshots {
+				result = append(result, s.Name)
+			}
+			return result
+		}
+
+		BeforeEach(func() {
+			now = time.Now()
+			ttl := int32(3600)
+			snapshot.Spec.TTLSecondsAfterCompletion = &ttl
+
+			list = &ApplicationSnapshotList{Items: []ApplicationSnapshot{
+				newSnapshot("app-a-oldest", "app-a", 3*time.Hour, true),
+				newSnapshot("app-a-older", "app-a", 2*time.Hour, true),
+				newSnapshot("app-a-recent", "app-a", 30*time.Minute, true),
+				newSnapshot("app-a-running", "app-a", 10*time.Minute, false),
+				newSnapshot("app-b-old", "app-b", 5*time.Hour, true),
+			}}
+		})
+
+		It("should collect the TTL-expired snapshots without count-based retention", func() {
+			Expect(names(list.Collectible(now, 0))).To(Equal([]string{"app-a-oldest", "app-a-older", "app-b-old"}))
+		})
+
+		It("should collect the done snapshots beyond the newest ones of each application", func() {
+			Expect(names(list.Collectible(now, 1))).To(Equal([]string{"app-a-oldest", "app-a-older"}))
+			Expect(names(list.Collectible(now, 2))).To(Equal([]string{"app-a-oldest"}))
+		})
+
+		It("should retain the newest snapshots of each application even when they are TTL-expired", func() {
+			list.Items = append(list.Items, newSnapshot("app-b-oldest", "app-b", 6*time.Hour, true))
+
+			Expect(names(list.Collectible(now, 1))).To(Equal([]string{"app-a-oldest", "app-a-older", "app-b-oldest"}))
+			Expect(names(list.Collectible(now, 3))).To(BeEmpty())
+		})
+
+		It("should never collect running snapshots", func() {
+			list.Items[3].Status.CompletionTime = nil
+			Expect(names(list.Collectible(now.Add(24*time.Hour), 0))).ToNot(ContainElement("app-a-running"))
+		})
+	})
+
+	Context("Testing ValidateSecureRegistries", func() {
+
+		It("should accept standard image references", func() {
+			Expect(snapshot.ValidateSecureRegistries()).To(Succeed())
+		})
+
+		It("should flag images from localhost or a loopback address", func() {
+			snapshot.Spec.Components = append(snapshot.Spec.Components,
+				ApplicationSnapshotComponent{Name: "component-c", ContainerImage: "localhost:5000/org/component-c:v1"},
+				ApplicationSnapshotComponent{Name: "component-d", ContainerImage: "127.0.0.1/org/component-d:v1"},
+				ApplicationSnapshotComponent{Name: "component-e", ContainerImage: "[::1]:5000/org/component-e:v1"})
+
+			err := snapshot.ValidateSecureRegistries()
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).ToNot(ContainSubstring("spec.components[1]"))
+			for _, index := range []int{2, 3, 4} {
+				Expect(err.Error()).To(ContainSubstring(fmt.Sprintf("spec.components[%d].containerImage", index)))
+			}
+		})
+
+		It("should flag images with an explicit http prefix", func() {
+			snapshot.Spec.Components[0].ContainerImage = "http://registry.example.com/org/component-a:v1"
+
+			err := snapshot.ValidateSecureRegistries()
+			Expect(err).ToNot(BeNil())
+			Expect(err.Error()).To(ContainSubstring("image of component component-a would be pulled from its registry over plain HTTP"))
+		})
+	})
+
+	Context("Testing PrimaryFailure", func() {
+
+		It("should return the single failed component", func() {
+			snapshot.Status.Components = []ApplicationSnapshotComponentStatus{
+				{Name: "component-a"},
+				{Name: "component-b", Failed: true, Message: "3 tests failed"},
+			}
+
+			status, ok := snapshot.PrimaryFailure()
+			Expect(ok).To(BeTrue())
+			Expect(status.Name).To(Equal("component-b"))
+			Expect(status.Message).To(Equal("3 tests failed"))
+		})
+
+		It("should deterministically pick the first of multiple failed components", func() {
+			snapshot.Status.Components = []ApplicationSnapshotComponentStatus{
+				{Name: "component-a", Failed: true},
+				{Name: "component-b", Failed: true},
+			}
+
+			for i := 0; i < 3; i++ {
+				status, ok := snapshot.PrimaryFailure()
+				Expect(ok).To(BeTrue())
+				Expect(status.Name).To(Equal("component-a"))
+			}
+		})
+
+		It("should prefer the failed component designated as primary", func() {
+			snapshot.Status.Components = []ApplicationSnapshotComponentStatus{
+				{Name: "component-a", Failed: true},
+				{Name: "component-b", Failed: true, Primary: true},
+			}
+
+			status, ok := snapshot.PrimaryFailure()
+			Expect(ok).To(BeTrue())
+			Expect(status.Name).To(Equal("component-b"))
+		})
+
+		It("should not return a failure when no component failed", func() {
+			snapshot.Status.Components = []ApplicationSnapshotComponentStatus{
+				{Name: "component-a"},
+				{Name: "component-b", Primary: true},
+			}
+
+			status, ok := snapshot.PrimaryFailure()
+			Expect(ok).To(BeFalse())
+			Expect(status).To(BeNil())
+		})
+	})
+
+	Context("Testing AuditRecord and AppendAuditRecord", func() {
+
+		BeforeEach(func() {
+			snapshot.UID = "1234"
+			snapshot.Generation = 3
+			snapshot.MarkRunning()
+			snapshot.MarkFailed(ApplicationSnapshotReasonTestsFailed, "2 tests failed")
+		})
+
+		It("should record the state of the snapshot", func() {
+			condition := meta.FindStatusCondition(snapshot.Status.Conditions, applicationSnapshotConditionType)
+
+			entry := snapshot.AuditRecord()
+			Expect(entry.Timestamp).To(Equal(condition.LastTransitionTime))
+			Expect(entry.ObjectRef.Kind).To(Equal("ApplicationSnapshot"))
+			Expect(entry.ObjectRef.APIVersion).To(Equal("appstudio.redhat.com/v1alpha1"))
+			Expect(entry.ObjectRef.Namespace).To(Equal("my-namespace"))
+			Expect(entry.ObjectRef.Name).To(Equal("my-snapshot"))
+			Expect(entry.ObjectRef.UID).To(Equal(types.UID("1234")))
+			Expect(entry.Phase).To(Equal(ApplicationSnapshotPhaseFailed))
+			Expect(entry.Reason).To(Equal(ApplicationSnapshotReasonTestsFailed.String()))
+			Expect(entry.Message).To(Equal("2 tests failed"))
+			Expect(entry.Generation).To(Equal(int64(3)))
+		})
+
+		It("should record a pending snapshot", func() {
+			snapshot.Status.Conditions = nil
+
+			entry := snapshot.AuditRecord()
+			Expect(entry.Phase).To(Equal(ApplicationSnapshotPhasePending))
+			Expect(entry.Reason).To(BeEmpty())
+			Expect(entry.Timestamp.IsZero()).To(BeFalse())
+		})
+
+		It("should append the record as a single JSON line", func() {
+			buffer := &strings.Builder{}
+			Expect(snapshot.AppendAuditRecord(buffer)).To(Succeed())
+			Expect(snapshot.AppendAuditRecord(buffer)).To(Succeed())
+
+			lines := strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n")
+			Expect(lines).To(HaveLen(2))
+
+			entry := AuditEntry{}
+			Expect(json.Unmarshal([]byte(lines[0]), &entry)).To(Succeed())
+			Expect(entry.ObjectRef.Name).To(Equal("my-snapshot"))
+			Expect(entry.Reason).To(Equal(ApplicationSnapshotReasonTestsFailed.String()))
+			Expect(entry.Generation).To(Equal(int64(3)))
+		})
+	})
+})
@@ -0,0 +1,720 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/distribution/reference"
+	admissionv1 "k8s.io/api/admission/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// log is for logging in this package.
+var applicationsnapshotlog = logf.Log.WithName("applicationsnapshot-resource")
+
+// applicationGroupVersionKind is the GroupVersionKind of the Application resource referenced by ApplicationSnapshots.
+// The Application API is defined by the application-service, so it is looked up as an unstructured object to avoid
+// depending on it.
+var applicationGroupVersionKind = schema.GroupVersionKind{Group: GroupVersion.Group, Version: GroupVersion.Version, Kind: "Application"}
+
+// SetupWebhookWithManager registers the ApplicationSnapshot webhooks with the manager, using the default validation options.
+// The webhooks aren't served by this module: operators consuming the API register them with their own manager, and
+// deploy the matching webhook configuration.
+func (r *ApplicationSnapshot) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	defaulter := &ApplicationSnapshotDefaulter{}
+	if err := defaulter.SetupWebhookWithManager(mgr); err != nil {
+		return err
+	}
+
+	validator := &ApplicationSnapshotValidator{Client: mgr.GetClient()}
+
+	return validator.SetupWebhookWithManager(mgr)
+}
+
+//+kubebuilder:webhook:path=/mutate-appstudio-redhat-com-v1alpha1-applicationsnapshot,mutating=true,failurePolicy=fail,sideEffects=None,groups=appstudio.redhat.com,resources=applicationsnapshots;applicationsnapshots/status,verbs=create;update,versions=v1alpha1,name=mapplicationsnapshot.kb.io,admissionReviewVersions=v1
+
+// applicationSnapshotMutatingWebhookPath is the path the ApplicationSnapshot defaulting webhook is served at
+const applicationSnapshotMutatingWebhookPath = "/mutate-appstudio-redhat-com-v1alpha1-applicationsnapshot"
+
+// ApplicationSnapshotDefaulter defaults ApplicationSnapshots on admission.
+// +kubebuilder:object:generate=false
+type ApplicationSnapshotDefaulter struct{}
+
+var _ admission.CustomDefaulter = &ApplicationSnapshotDefaulter{}
+
+// SetupWebhookWithManager registers the ApplicationSnapshot defaulting webhook with the manager, using this defaulter.
+func (d *ApplicationSnapshotDefaulter) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	mgr.GetWebhookServer().Register(applicationSnapshotMutatingWebhookPath, admission.WithCustomDefaulter(&ApplicationSnapshot{}, d))
+
+	return nil
+}
+
+// Default implements admission.CustomDefaulter. It coerces the legacy types of ApplicationSnapshots to the supported ones,
+// and sanitizes their status conditions, including on updates of the status subresource.
+func (d *ApplicationSnapshotDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	snapshot, err := toApplicationSnapshot(obj)
+	if err != nil {
+		return err
+	}
+	applicationsnapshotlog.Info("default", "name", snapshot.Name)
+
+	snapshot.NormalizeType()
+	snapshot.Sanitize()
+
+	return nil
+}
+
+//+kubebuilder:webhook:path=/validate-appstudio-redhat-com-v1alpha1-applicationsnapshot,mutating=false,failurePolicy=fail,sideEffects=None,groups=appstudio.redhat.com,resources=applicationsnapshots;applicationsnapshots/status,verbs=create;update,versions=v1alpha1,name=vapplicationsnapshot.kb.io,admissionReviewVersions=v1
+
+// applicationSnapshotValidatingWebhookPath is the path the ApplicationSnapshot validating webhook is served at
+const applicationSnapshotValidatingWebhookPath = "/validate-appstudio-redhat-com-v1alpha1-applicationsnapshot"
+
+// DefaultFloatingTags is the default list of floating image tags the ApplicationSnapshotValidator warns about
+var DefaultFloatingTags = []string{"latest", "main", "master"}
+
+// ApplicationSnapshotValidator validates ApplicationSnapshots on admission.
+// +kubebuilder:object:generate=false
+type ApplicationSnapshotValidator struct {
+	// Client is used to look up the resources referenced by the ApplicationSnapshots being validated
+	Client client.Client
+
+	// ValidateApplicationExists enables rejecting the creation of ApplicationSnapshots whose Application doesn't exist
+	// in the same namespace. It is disabled by default, since some flows create ApplicationSnapshots before their Application.
+	ValidateApplicationExists bool
+
+	// FloatingTags is the list of image tags which are expected to move over time, such as branch names. A warning is
+	// returned for components whose image uses one of these tags without being pinned by digest. When nil,
+	// DefaultFloatingTags is used.
+	FloatingTags []string
+
+	// RequireSingleRegistry enables rejecting ApplicationSnapshots whose component images don't all come from the same
+	// registry, for air-gapped environments where every image must be pulled from a single mirror.
+	RequireSingleRegistry bool
+
+	// WarnOnSecretLikeDescriptions enables warning about DisplayDescriptions which look like they contain secrets, such
+	// as access keys, bearer tokens or private keys. The check is heuristic, so it never rejects the request.
+	WarnOnSecretLikeDescriptions bool
+
+	// RejectMixedComponentSources enables rejecting composite ApplicationSnapshots which mix components built from
+	// source, that is with an ImageSource in the Artifacts, and prebuilt components without any. It is disabled by
+	// default, so mixed composites are allowed unless the policy requires otherwise.
+	RejectMixedComponentSources bool
+
+	// MaxSize is the size budget, in bytes, of the ApplicationSnapshots, above which they are rejected with a clear
+	// error before reaching the size limit of the API server. When zero, MaxApplicationSnapshotSize is used, and a
+	// negative value disables the check.
+	MaxSize int
+
+	decoder *admission.Decoder
+}
+
+var _ admission.CustomValidator = &ApplicationSnapshotValidator{}
+var _ admission.Handler = &ApplicationSnapshotValidator{}
+
+// SetupWebhookWithManager registers the ApplicationSnapshot webhooks with the manager, using this validator.
+//
+// The validator is registered as an admission.Handler rather than through the webhook builder, so that the
+// warnings returned by Warnings can be included in the admission response.
+func (v *ApplicationSnapshotValidator) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	mgr.GetWebhookServer().Register(applicationSnapshotValidatingWebhookPath, &webhook.Admission{Handler: v})
+
+	return nil
+}
+
+// InjectDecoder implements admission.DecoderInjector, so that the webhook server provides the decoder used by Handle.
+func (v *ApplicationSnapshotValidator) InjectDecoder(decoder *admission.Decoder) error {
+	v.decoder = decoder
+
+	return nil
+}
+
+// Handle implements admission.Handler. It runs the CustomValidator function matching the operation of the request
+// (or ValidateStatusUpdate, for updates of the status subresource), and attaches the warnings of the
+// ApplicationSnapshot to the response when it is allowed.
+func (v *ApplicationSnapshotValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	snapshot := &ApplicationSnapshot{}
+
+	var err error
+	switch req.Operation {
+	case admissionv1.Create:
+		if err := v.decoder.Decode(req, snapshot); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		err = v.ValidateCreate(ctx, snapshot)
+
+	case admissionv1.Update:
+		oldSnapshot := &ApplicationSnapshot{}
+		if err := v.decoder.Decode(req, snapshot); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		if err := v.decoder.DecodeRaw(req.OldObject, oldSnapshot); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		if req.SubResource == "status" {
+			return toAdmissionResponse(v.ValidateStatusUpdate(ctx, oldSnapshot, snapshot))
+		}
+		err = v.ValidateUpdate(ctx, oldSnapshot, snapshot)
+
+	case admissionv1.Delete:
+		if err := v.decoder.DecodeRaw(req.OldObject, snapshot); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		return toAdmissionResponse(v.ValidateDelete(ctx, snapshot))
+
+	default:
+		return admission.Allowed("")
+	}
+
+	if err != nil {
+		return toAdmissionResponse(err)
+	}
+
+	return admission.Allowed("").WithWarnings(v.Warnings(snapshot)...)
+}
+
+// toAdmissionResponse converts the error returned by a validation function into an admission response, preserving
+// the details of API status errors.
+func toAdmissionResponse(err error) admission.Response {
+	if err == nil {
+		return admission.Allowed("")
+	}
+
+	if apiStatus, ok := err.(apierrors.APIStatus); ok {
+		status := apiStatus.Status()
+		return admission.Response{
+			AdmissionResponse: admissionv1.AdmissionResponse{
+				Allowed: false,
+				Result:  &status,
+			},
+		}
+	}
+
+	return admission.Denied(err.Error())
+}
+
+// Warnings returns the non-fatal issues found with the ApplicationSnapshot, which are reported to the user on
+// admission without rejecting the request.
+func (v *ApplicationSnapshotValidator) Warnings(snapshot *ApplicationSnapshot) []string {
+	warnings := []string{}
+	warnings = append(warnings, sharedDigestWarnings(snapshot.Spec.Components)...)
+	warnings = append(warnings, v.floatingTagWarnings(snapshot.Spec.Components)...)
+	warnings = append(warnings, selfReferenceWarnings(snapshot)...)
+	if v.WarnOnSecretLikeDescriptions {
+		warnings = append(warnings, secretLikeDescriptionWarnings(snapshot.Spec.DisplayDescription)...)
+	}
+
+	return warnings
+}
+
+// floatingTagWarnings returns a warning for each component whose image uses a floating tag without being pinned by
+// digest. An image without a tag nor a digest implicitly uses the 'latest' tag.
+func (v *ApplicationSnapshotValidator) floatingTagWarnings(components []ApplicationSnapshotComponent) []string {
+	warnings := []string{}
+
+	floatingTags := v.FloatingTags
+	if floatingTags == nil {
+		floatingTags = DefaultFloatingTags
+	}
+
+	for _, component := range components {
+		named, err := reference.ParseNormalizedNamed(component.ContainerImage)
+		if err != nil {
+			continue
+		}
+		if _, isDigested := named.(reference.Digested); isDigested {
+			continue
+		}
+
+		tag := "latest"
+		if tagged, isTagged := named.(reference.Tagged); isTagged {
+			tag = tagged.Tag()
+		}
+
+		if contains(floatingTags, tag) {
+			warnings = append(warnings, fmt.Sprintf("component %s uses the floating image tag %q without a digest, so the deployed image may change over time", component.Name, tag))
+		}
+	}
+
+	return warnings
+}
+
+// secretLikePatterns are the patterns of common secrets which are not expected in a DisplayDescription
+var secretLikePatterns = map[string]*regexp.Regexp{
+	"an AWS access key":    regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`),
+	"a bearer token":       regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9\-._~+/]{16,}=*`),
+	"a GitHub token":       regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`),
+	"a PEM encoded secret": regexp.MustCompile(`-----BEGIN [A-Z ]+-----`),
+}
+
+// secretLikeDescriptionWarnings returns a warning for each kind of secret the description looks like it contains. The
+// matched values are not included in the warnings, so that they aren't leaked any further.
+func secretLikeDescriptionWarnings(description string) []string {
+	warnings := []string{}
+
+	kinds := []string{}
+	for kind, pattern := range secretLikePatterns {
+		if pattern.MatchString(description) {
+			kinds = append(kinds, kind)
+		}
+	}
+	sort.Strings(kinds)
+
+	for _, kind := range kinds {
+		warnings = append(warnings, fmt.Sprintf("the display description looks like it contains %s, which should not be stored in a snapshot", kind))
+	}
+
+	return warnings
+}
+
+// selfReferenceWarnings returns a warning when the ApplicationSnapshot references an Application with its own name,
+// which is almost always a misconfiguration.
+func selfReferenceWarnings(snapshot *ApplicationSnapshot) []string {
+	if snapshot.Spec.Application == "" || snapshot.Spec.Application != snapshot.Name {
+		return []string{}
+	}
+
+	return []string{fmt.Sprintf("snapshot %s references an application with its own name, which is likely a misconfiguration", snapshot.Name)}
+}
+
+// sharedDigestWarnings returns a warning for each image digest which is referenced by differently-named components.
+// While this may be legitimate, it often indicates that an image was copied to the wrong component by mistake.
+func sharedDigestWarnings(components []ApplicationSnapshotComponent) []string {
+	warnings := []string{}
+
+	componentsByDigest := map[string][]string{}
+	digests := []string{}
+	for _, component := range components {
+		named, err := reference.ParseNormalizedNamed(component.ContainerImage)
+		if err != nil {
+			continue
+		}
+		digested, isDigested := named.(reference.Digested)
+		if !isDigested {
+			continue
+		}
+
+		digest := digested.Digest().String()
+		if _, exists := componentsByDigest[digest]; !exists {
+			digests = append(digests, digest)
+		}
+		if !contains(componentsByDigest[digest], component.Name) {
+			componentsByDigest[digest] = append(componentsByDigest[digest], component.Name)
+		}
+	}
+
+	for _, digest := range digests {
+		if names := componentsByDigest[digest]; len(names) > 1 {
+			warnings = append(warnings, fmt.Sprintf("components %s share the same image digest %s", strings.Join(names, ", "), digest))
+		}
+	}
+
+	return warnings
+}
+
+// ValidateCreate implements admission.CustomValidator so a webhook will be registered for the type
+func (v *ApplicationSnapshotValidator) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+	snapshot, err := toApplicationSnapshot(obj)
+	if err != nil {
+		return err
+	}
+	applicationsnapshotlog.Info("validate create", "name", snapshot.Name)
+
+	allErrs := v.validateApplicationSnapshot(snapshot)
+	if v.ValidateApplicationExists {
+		allErrs = append(allErrs, v.validateApplicationExists(ctx, snapshot)...)
+	}
+
+	return toInvalidError(snapshot, allErrs)
+}
+
+// ValidateUpdate implements admission.CustomValidator so a webhook will be registered for the type
+func (v *ApplicationSnapshotValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) error {
+	snapshot, err := toApplicationSnapshot(newObj)
+	if err != nil {
+		return err
+	}
+	applicationsnapshotlog.Info("validate update", "name", snapshot.Name)
+
+	return toInvalidError(snapshot, v.validateApplicationSnapshot(snapshot))
+}
+
+// validateApplicationSnapshot runs the stateless validations of ValidateApplicationSnapshot, along with the optional
+// validations enabled on the validator.
+func (v *ApplicationSnapshotValidator) validateApplicationSnapshot(snapshot *ApplicationSnapshot) field.ErrorList {
+	allErrs := ValidateApplicationSnapshot(snapshot)
+	if v.RequireSingleRegistry {
+		allErrs = append(allErrs, validateSingleRegistry(snapshot.Spec.Components, field.NewPath("spec").Child("components"))...)
+	}
+	if v.RejectMixedComponentSources {
+		allErrs = append(allErrs, validateHomogeneousComponentSources(snapshot, field.NewPath("spec").Child("components"))...)
+	}
+	if maxSize := v.maxSize(); maxSize > 0 {
+		allErrs = append(allErrs, validateSizeBudget(snapshot, maxSize, field.NewPath("spec"))...)
+	}
+
+	return allErrs
+}
+
+// maxSize returns the size budget of the ApplicationSnapshots, defaulting to MaxApplicationSnapshotSize.
+func (v *ApplicationSnapshotValidator) maxSize() int {
+	if v.MaxSize == 0 {
+		return MaxApplicationSnapshotSize
+	}
+
+	return v.MaxSize
+}
+
+// ValidateStatusUpdate validates an update of the status subresource of an ApplicationSnapshot, checking that
+// the new status is internally consistent.
+func (v *ApplicationSnapshotValidator) ValidateStatusUpdate(ctx context.Context, oldObj, newObj runtime.Object) error {
+	snapshot, err := toApplicationSnapshot(newObj)
+	if err != nil {
+		return err
+	}
+	applicationsnapshotlog.Info("validate status update", "name", snapshot.Name)
+
+	return toInvalidError(snapshot, validateStatusConsistency(snapshot, field.NewPath("status")))
+}
+
+// ValidateDelete implements admission.CustomValidator so a webhook will be registered for the type
+func (v *ApplicationSnapshotValidator) ValidateDelete(ctx context.Context, obj runtime.Object) error {
+	snapshot, err := toApplicationSnapshot(obj)
+	if err != nil {
+		return err
+	}
+	applicationsnapshotlog.Info("validate delete", "name", snapshot.Name)
+
+	return nil
+}
+
+// validateApplicationExists checks that the Application referenced by the ApplicationSnapshot exists in its namespace.
+func (v *ApplicationSnapshotValidator) validateApplicationExists(ctx context.Context, snapshot *ApplicationSnapshot) field.ErrorList {
+	allErrs := field.ErrorList{}
+	applicationPath := field.NewPath("spec").Child("application")
+
+	if snapshot.Spec.Application == "" {
+		// A missing Application is reported by the stateless validations
+		return allErrs
+	}
+
+	application := &unstructured.Unstructured{}
+	application.SetGroupVersionKind(applicationGroupVersionKind)
+
+	err := v.Client.Get(ctx, client.ObjectKey{Namespace: snapshot.Namespace, Name: snapshot.Spec.Application}, application)
+	if apierrors.IsNotFound(err) {
+		allErrs = append(allErrs, field.NotFound(applicationPath, snapshot.Spec.Application))
+	} else if err != nil {
+		allErrs = append(allErrs, field.InternalError(applicationPath, fmt.Errorf("unable to retrieve Application: %v", err)))
+	}
+
+	return allErrs
+}
+
+// toApplicationSnapshot casts the given object to an ApplicationSnapshot, returning an error if it isn't one.
+func toApplicationSnapshot(obj runtime.Object) (*ApplicationSnapshot, error) {
+	snapshot, ok := obj.(*ApplicationSnapshot)
+	if !ok {
+		return nil, apierrors.NewBadRequest(fmt.Sprintf("expected an ApplicationSnapshot but got a %T", obj))
+	}
+
+	return snapshot, nil
+}
+
+// toInvalidError returns an Invalid error containing every given violation, or nil if there are none.
+func toInvalidError(snapshot *ApplicationSnapshot, allErrs field.ErrorList) error {
+	if len(allErrs) == 0 {
+		return nil
+	}
+
+	return apierrors.NewInvalid(GroupVersion.WithKind("ApplicationSnapshot").GroupKind(), snapshot.Name, allErrs)
+}
+
+// ValidateApplicationSnapshot runs all the stateless validations of the ApplicationSnapshot, and returns every
+// violation that was found. It doesn't require access to a cluster, and can thus be used outside of admission
+// (for example, to validate ApplicationSnapshot manifests in CI before they are applied).
+func ValidateApplicationSnapshot(s *ApplicationSnapshot) field.ErrorList {
+	specPath := field.NewPath("spec")
+
+	allErrs := field.ErrorList{}
+	allErrs = append(allErrs, validateComponents(s.Spec.Components, specPath.Child("components"))...)
+	allErrs = append(allErrs, validateType(s.Spec.Type, specPath.Child("type"))...)
+	allErrs = append(allErrs, validateDisplayFields(s.Spec, specPath)...)
+	allErrs = append(allErrs, validateTTLSecondsAfterCompletion(s.Spec.TTLSecondsAfterCompletion, specPath.Child("ttlSecondsAfterCompletion"))...)
+	allErrs = append(allErrs, validateArtifacts(s.Spec.Artifacts, specPath.Child("artifacts"))...)
+	allErrs = append(allErrs, validateArtifactImages(s.Spec, specPath.Child("artifacts").Child("images"))...)
+
+	return allErrs
+}
+
+// validateComponents checks that every component has a unique, non-empty and DNS-1123 compliant name,
+// a valid container image reference, syntactically valid labels, and a weight within bounds. Exact duplicates of a
+// previous component, with the same name and container image, are reported on the whole entry, so that they can be
+// told apart from different components sharing a name.
+func validateComponents(components []ApplicationSnapshotComponent, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	seenImages := map[string]string{}
+
+	for i, component := range components {
+		namePath := fldPath.Index(i).Child("name")
+		imagePath := fldPath.Index(i).Child("containerImage")
+
+		if component.Name == "" {
+			allErrs = append(allErrs, field.Required(namePath, "component name must not be empty"))
+		} else {
+			for _, msg := range validation.IsDNS1123Label(component.Name) {
+				allErrs = append(allErrs, field.Invalid(namePath, component.Name, msg))
+			}
+
+			if image, seen := seenImages[component.Name]; !seen {
+				seenImages[component.Name] = component.ContainerImage
+			} else if image == component.ContainerImage {
+				allErrs = append(allErrs, field.Duplicate(fldPath.Index(i), component.Name+"="+component.ContainerImage))
+			} else {
+				allErrs = append(allErrs, field.Duplicate(namePath, component.Name))
+			}
+		}
+
+		if component.ContainerImage == "" {
+			allErrs = append(allErrs, field.Required(imagePath, "component container image must not be empty"))
+		} else if _, err := reference.ParseNormalizedNamed(component.ContainerImage); err != nil {
+			allErrs = append(allErrs, field.Invalid(imagePath, component.ContainerImage, err.Error()))
+		}
+
+		allErrs = append(allErrs, metav1validation.ValidateLabels(component.Labels, fldPath.Index(i).Child("labels"))...)
+
+		if component.Weight != nil && (*component.Weight < 0 || *component.Weight > MaxComponentWeight) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Index(i).Child("weight"), *component.Weight,
+				fmt.Sprintf("must be between 0 and %d", MaxComponentWeight)))
+		}
+	}
+
+	return allErrs
+}
+
+// validateType checks that the ApplicationSnapshot type, if set, is one of the supported types.
+func validateType(snapshotType string, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	supportedTypes := []string{ApplicationSnapshotTypeComponent, ApplicationSnapshotTypeComposite}
+	if snapshotType != "" && !contains(supportedTypes, snapshotType) {
+		allErrs = append(allErrs, field.NotSupported(fldPath, snapshotType, supportedTypes))
+	}
+
+	return allErrs
+}
+
+// validateDisplayFields checks that the user-visible display fields of the ApplicationSnapshot are within their length limits.
+func validateDisplayFields(spec ApplicationSnapshotSpec, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	if len(spec.DisplayName) > MaxDisplayNameLength {
+		allErrs = append(allErrs, field.TooLong(fldPath.Child("displayName"), spec.DisplayName, MaxDisplayNameLength))
+	}
+	if len(spec.DisplayDescription) > MaxDisplayDescriptionLength {
+		allErrs = append(allErrs, field.TooLong(fldPath.Child("displayDescription"), spec.DisplayDescription, MaxDisplayDescriptionLength))
+	}
+
+	return allErrs
+}
+
+// validateTTLSecondsAfterCompletion checks that the TTLSecondsAfterCompletion of the ApplicationSnapshot, if set, is
+// neither negative nor above MaxTTLSecondsAfterCompletion.
+func validateTTLSecondsAfterCompletion(ttl *int32, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	if ttl == nil {
+		return allErrs
+	}
+	if *ttl < 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath, *ttl, "must be greater than or equal to 0"))
+	} else if *ttl > MaxTTLSecondsAfterCompletion {
+		allErrs = append(allErrs, field.Invalid(fldPath, *ttl, fmt.Sprintf("must be less than or equal to %d", MaxTTLSecondsAfterCompletion)))
+	}
+
+	return allErrs
+}
+
+// validateArtifacts checks the Artifacts of an ApplicationSnapshot, returning the list of violations.
+func validateArtifacts(artifacts SnapshotArtifacts, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	if artifacts.UnstableFields == nil || len(artifacts.UnstableFields.Raw) == 0 {
+		return allErrs
+	}
+
+	unstableFieldsPath := fldPath.Child("unstableFields")
+	raw := artifacts.UnstableFields.Raw
+
+	if len(raw) > MaxArtifactsUnstableFieldsSize {
+		allErrs = append(allErrs, field.TooLong(unstableFieldsPath, "", MaxArtifactsUnstableFieldsSize))
+	} else if !json.Valid(raw) {
+		allErrs = append(allErrs, field.Invalid(unstableFieldsPath, string(raw), "must be valid JSON"))
+	}
+
+	return allErrs
+}
+
+// validateArtifactImages checks that every ImageSource of the Artifacts references a component of the ApplicationSnapshot.
+func validateArtifactImages(spec ApplicationSnapshotSpec, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	componentNames := []string{}
+	for _, component := range spec.Components {
+		componentNames = append(componentNames, component.Name)
+	}
+
+	for i, imageSource := range spec.Artifacts.Images {
+		if !contains(componentNames, imageSource.Component) {
+			allErrs = append(allErrs, field.Invalid(fldPath.Index(i).Child("component"), imageSource.Component,
+				"must reference a component of the snapshot"))
+		}
+	}
+
+	return allErrs
+}
+
+// validateSingleRegistry checks that the container images of all the components come from the registry of the
+// first component's image. Images which can't be parsed are ignored, since they are reported by validateComponents.
+func validateSingleRegistry(components []ApplicationSnapshotComponent, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	registry, registryComponent := "", ""
+	for i, component := range components {
+		named, err := reference.ParseNormalizedNamed(component.ContainerImage)
+		if err != nil {
+			continue
+		}
+
+		domain := reference.Domain(named)
+		if registryComponent == "" {
+			registry, registryComponent = domain, component.Name
+		} else if domain != registry {
+			allErrs = append(allErrs, field.Invalid(fldPath.Index(i).Child("containerImage"), component.ContainerImage,
+				fmt.Sprintf("registry %s differs from the registry %s of component %s", domain, registry, registryComponent)))
+		}
+	}
+
+	return allErrs
+}
+
+// validateHomogeneousComponentSources checks that the components of a composite ApplicationSnapshot are either all
+// built from source, that is referenced by an ImageSource of the Artifacts, or all prebuilt. The components which
+// differ from the first component are reported. ApplicationSnapshots of other types are not checked.
+func validateHomogeneousComponentSources(snapshot *ApplicationSnapshot, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if snapshot.Spec.Type != ApplicationSnapshotTypeComposite || len(snapshot.Spec.Components) == 0 {
+		return allErrs
+	}
+
+	withSource := map[string]bool{}
+	for _, image := range snapshot.Spec.Artifacts.Images {
+		withSource[image.Component] = true
+	}
+
+	first := snapshot.Spec.Components[0]
+	for i, component := range snapshot.Spec.Components[1:] {
+		if withSource[component.Name] != withSource[first.Name] {
+			allErrs = append(allErrs, field.Invalid(fldPath.Index(i+1).Child("name"), component.Name,
+				fmt.Sprintf("composite snapshots can't mix components with and without a source, unlike component %s", first.Name)))
+		}
+	}
+
+	return allErrs
+}
+
+// validateSizeBudget checks that the JSON representation of the ApplicationSnapshot is no larger than the given
+// budget, in bytes. The error is reported on the spec, since the components and artifacts are what grows.
+func validateSizeBudget(snapshot *ApplicationSnapshot, max int, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	size, err := snapshot.marshaledSize()
+	if err != nil {
+		allErrs = append(allErrs, field.InternalError(fldPath, err))
+	} else if size > max {
+		allErrs = append(allErrs, field.Forbidden(fldPath,
+			fmt.Sprintf("the ApplicationSnapshot is %d bytes, over the size budget of %d bytes", size, max)))
+	}
+
+	return allErrs
+}
+
+// validateStatusConsistency checks the invariants of the ApplicationSnapshot status: the completion time can't be
+// before the start time, a running ApplicationSnapshot can't have a completion time and must reference its integration
+// test PipelineRun, an ApplicationSnapshot which is done must have a completion time, and every condition must have a
+// last transition time.
+func validateStatusConsistency(snapshot *ApplicationSnapshot, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	status := snapshot.Status
+	completionTimePath := fldPath.Child("completionTime")
+
+	if status.StartTime != nil && status.CompletionTime != nil && status.CompletionTime.Before(status.StartTime) {
+		allErrs = append(allErrs, field.Invalid(completionTimePath, status.CompletionTime.String(), "must not be before the start time"))
+	}
+
+	condition := meta.FindStatusCondition(status.Conditions, applicationSnapshotConditionType)
+	if condition != nil && condition.Status == metav1.ConditionUnknown && status.CompletionTime != nil {
+		allErrs = append(allErrs, field.Forbidden(completionTimePath, "must not be set while the snapshot is running"))
+	}
+	if condition != nil && condition.Status == metav1.ConditionUnknown &&
+		condition.Reason == ApplicationSnapshotReasonTestsRunning.String() && status.IntegrationTestPipelineRun == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("integrationTestPipelineRun"), "must be set while the integration tests are running"))
+	}
+
+	if snapshot.IsDone() && status.CompletionTime == nil {
+		allErrs = append(allErrs, field.Required(completionTimePath, "must be set once the snapshot is done"))
+	}
+
+	for i, condition := range status.Conditions {
+		if condition.LastTransitionTime.IsZero() {
+			allErrs = append(allErrs, field.Required(fldPath.Child("conditions").Index(i).Child("lastTransitionTime"),
+				"must be set on every condition"))
+		}
+	}
+
+	return allErrs
+}
+
+// contains returns true if the given string is present in the slice, and false otherwise.
+func contains(slice []string, str string) bool {
+	for _, s := range slice {
+		if s == str {
+			return true
+		}
+	}
+
+	return false
+}
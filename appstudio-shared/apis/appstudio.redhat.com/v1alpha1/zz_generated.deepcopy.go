@@ -159,6 +159,18 @@ func (in *ApplicationSnapshot) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ApplicationSnapshotComponent) DeepCopyInto(out *ApplicationSnapshotComponent) {
 	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Weight != nil {
+		in, out := &in.Weight, &out.Weight
+		*out = new(int32)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApplicationSnapshotComponent.
@@ -171,6 +183,21 @@ func (in *ApplicationSnapshotComponent) DeepCopy() *ApplicationSnapshotComponent
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApplicationSnapshotComponentStatus) DeepCopyInto(out *ApplicationSnapshotComponentStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApplicationSnapshotComponentStatus.
+func (in *ApplicationSnapshotComponentStatus) DeepCopy() *ApplicationSnapshotComponentStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ApplicationSnapshotComponentStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ApplicationSnapshotEnvironmentBinding) DeepCopyInto(out *ApplicationSnapshotEnvironmentBinding) {
 	*out = *in
@@ -324,9 +351,16 @@ func (in *ApplicationSnapshotSpec) DeepCopyInto(out *ApplicationSnapshotSpec) {
 	if in.Components != nil {
 		in, out := &in.Components, &out.Components
 		*out = make([]ApplicationSnapshotComponent, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 	in.Artifacts.DeepCopyInto(&out.Artifacts)
+	if in.TTLSecondsAfterCompletion != nil {
+		in, out := &in.TTLSecondsAfterCompletion, &out.TTLSecondsAfterCompletion
+		*out = new(int32)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApplicationSnapshotSpec.
@@ -357,6 +391,32 @@ func (in *ApplicationSnapshotStatus) DeepCopyInto(out *ApplicationSnapshotStatus
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.BuildPipelineRuns != nil {
+		in, out := &in.BuildPipelineRuns, &out.BuildPipelineRuns
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.LastRetryTime != nil {
+		in, out := &in.LastRetryTime, &out.LastRetryTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Bindings != nil {
+		in, out := &in.Bindings, &out.Bindings
+		*out = make([]BindingRef, len(*in))
+		copy(*out, *in)
+	}
+	if in.ReleasedEnvironments != nil {
+		in, out := &in.ReleasedEnvironments, &out.ReleasedEnvironments
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Components != nil {
+		in, out := &in.Components, &out.Components
+		*out = make([]ApplicationSnapshotComponentStatus, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApplicationSnapshotStatus.
@@ -445,6 +505,21 @@ func (in *BindingComponentGitOpsRepository) DeepCopy() *BindingComponentGitOpsRe
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BindingRef) DeepCopyInto(out *BindingRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BindingRef.
+func (in *BindingRef) DeepCopy() *BindingRef {
+	if in == nil {
+		return nil
+	}
+	out := new(BindingRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BindingStatusGitOpsDeployment) DeepCopyInto(out *BindingStatusGitOpsDeployment) {
 	*out = *in
@@ -606,6 +681,21 @@ func (in *EnvironmentStatus) DeepCopy() *EnvironmentStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageSource) DeepCopyInto(out *ImageSource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageSource.
+func (in *ImageSource) DeepCopy() *ImageSource {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ManualPromotionConfiguration) DeepCopyInto(out *ManualPromotionConfiguration) {
 	*out = *in
@@ -644,6 +734,11 @@ func (in *SnapshotArtifacts) DeepCopyInto(out *SnapshotArtifacts) {
 		*out = new(v1.JSON)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Images != nil {
+		in, out := &in.Images, &out.Images
+		*out = make([]ImageSource, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SnapshotArtifacts.